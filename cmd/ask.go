@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/credential"
+	"github.com/MenciusCheng/go-cli/util/output"
 	"github.com/MenciusCheng/go-cli/util/strategy"
-	"github.com/MenciusCheng/go-cli/util/strategy/ask_strategy"
 	"github.com/spf13/cobra"
 	"strings"
 )
@@ -16,15 +18,31 @@ var askArgs = struct {
 	SelectionEndColumn   string
 	SelectedText         string
 	FileText             string
+	Language             string
 	DeepseekApiKey       string
 	QwenApiKey           string
+	Provider             string
+	Model                string
+	BaseURL              string
+	TabSize              int
+	Interactive          bool
+	SessionID            string
+	Format               string
+	OutputMode           string
+	Strategy             string
+	Explain              bool
 }{}
 
 var askCmd = &cobra.Command{
 	Use:   "ask [prompt]",
 	Short: "咨询大模型问题",
-	Long:  `向大模型提问并获得回答。必须提供问题作为参数。`,
-	Args:  cobra.MinimumNArgs(1), // 至少需要一个参数
+	Long:  `向大模型提问并获得回答。必须提供问题作为参数，使用 -i 进入多轮对话时可以省略。`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if askArgs.Interactive {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args) // 至少需要一个参数
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// 将所有参数连接成一个问题
 		prompt := strings.Join(args, " ")
@@ -42,8 +60,19 @@ func init() {
 	askCmd.Flags().StringVar(&askArgs.SelectionEndColumn, "selectionEndColumn", "", "选择结束列号")
 	askCmd.Flags().StringVar(&askArgs.SelectedText, "selectedText", "", "选中的文本内容")
 	askCmd.Flags().StringVar(&askArgs.FileText, "fileText", "", "完整文件文本内容")
-	askCmd.Flags().StringVar(&askArgs.DeepseekApiKey, "deepseekApiKey", "", "deepseek api key")
-	askCmd.Flags().StringVar(&askArgs.QwenApiKey, "qwenApiKey", "", "qwen api key")
+	askCmd.Flags().StringVar(&askArgs.Language, "language", "", "文件语言，如 go、sql、shell（为空时根据 modeline、扩展名和 shebang 自动识别）")
+	askCmd.Flags().StringVar(&askArgs.DeepseekApiKey, "deepseekApiKey", "", apiKeyFlagUsage("deepseek"))
+	askCmd.Flags().StringVar(&askArgs.QwenApiKey, "qwenApiKey", "", apiKeyFlagUsage("qwen"))
+	askCmd.Flags().StringVar(&askArgs.Provider, "provider", "", providerFlagUsage())
+	askCmd.Flags().StringVar(&askArgs.Model, "model", "", modelFlagUsage())
+	askCmd.Flags().IntVar(&askArgs.TabSize, "tabSize", strategy.DefaultTabSize, "制表符宽度，用于换算 IDE 传入的列号")
+	askCmd.Flags().StringVar(&askArgs.BaseURL, "baseURL", "", "自定义服务地址，如 http://localhost:11434/v1")
+	askCmd.Flags().StringVar(&askArgs.Format, "format", "", formatFlagUsage())
+	askCmd.Flags().StringVar(&askArgs.OutputMode, "output", "", outputFlagUsage())
+	askCmd.Flags().StringVar(&askArgs.Strategy, "strategy", "", strategyFlagUsage(askStrategies()))
+	askCmd.Flags().BoolVar(&askArgs.Explain, "explain", false, "只输出每个策略匹配或不匹配的原因，不执行策略")
+	askCmd.Flags().StringVar(&askArgs.SessionID, "session", "", "继续指定编号的会话，last 表示最近的会话")
+	askCmd.Flags().BoolVarP(&askArgs.Interactive, "interactive", "i", false, "进入多轮交互式对话，同 go-cli chat")
 
 	rootCmd.AddCommand(askCmd)
 }
@@ -59,16 +88,36 @@ func askHandler(prompt string) error {
 		SelectionEndColumn:   parseIntOrDefault(askArgs.SelectionEndColumn, 0),
 		SelectedText:         askArgs.SelectedText,
 		FileText:             askArgs.FileText,
-		DeepseekApiKey:       askArgs.DeepseekApiKey,
-		QwenApiKey:           askArgs.QwenApiKey,
+		Language:             askArgs.Language,
+		DeepseekApiKey:       credential.Secret(askArgs.DeepseekApiKey),
+		QwenApiKey:           credential.Secret(askArgs.QwenApiKey),
+		Provider:             askArgs.Provider,
+		Model:                askArgs.Model,
+		BaseURL:              askArgs.BaseURL,
+		TabSize:              askArgs.TabSize,
+		Interactive:          askArgs.Interactive,
+		SessionID:            askArgs.SessionID,
+		Format:               askArgs.Format,
+		OutputMode:           askArgs.OutputMode,
+		Strategy:             askArgs.Strategy,
+		Explain:              askArgs.Explain,
+	}
+
+	if err := applyConfig(e); err != nil {
+		return err
 	}
 
-	sm := strategy.NewStrategyManager()
-	sm.RegisterStrategies(
-		ask_strategy.NewAskCodeStrategy(),
-		ask_strategy.NewAskAnyStrategy(),
-		strategy.NewEchoStrategy(),
-	)
+	return newStrategyManager(withRuleStrategies("ask", askStrategies())).HandleEvent(e)
+}
+
+// formatFlagUsage 生成 --format 参数的帮助信息
+func formatFlagUsage() string {
+	return fmt.Sprintf("回答的输出格式，可选值: %s（为空时在终端中渲染 markdown，输出到管道或 IDE 控制台时原样输出）",
+		strings.Join(strategy.Formats, ", "))
+}
 
-	return sm.HandleEvent(e)
+// outputFlagUsage 生成 --output 参数的帮助信息
+func outputFlagUsage() string {
+	return fmt.Sprintf("输出模式，可选值: %s（默认 %s；json 结束后输出一个对象，ndjson 每行输出一个事件，供编辑器插件解析）",
+		strings.Join(output.Modes, ", "), output.Text)
 }
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/credential"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var authArgs = struct {
+	Store string
+}{}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "管理大模型 api key",
+	Long: `安全地保存大模型 api key，避免通过命令行参数传入（会暴露在进程列表和 shell 历史中）。
+
+api key 优先保存到系统钥匙环（需要 secret-tool 和 D-Bus 会话），不可用时保存到加密的本地凭证文件。
+查找顺序：命令行参数 > 环境变量 > 系统钥匙环 > 本地凭证文件。`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login [provider]",
+	Short: "保存提供方的 api key，从标准输入读取",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return authLoginHandler(args[0])
+	},
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout [provider]",
+	Short: "删除已保存的 api key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return authLogoutHandler(args[0])
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "查看各提供方 api key 的来源",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return authStatusHandler()
+	},
+}
+
+func init() {
+	authLoginCmd.Flags().StringVar(&authArgs.Store, "store", "", "凭证存储: keyring 或 file，为空时自动选择")
+
+	authCmd.AddCommand(authLoginCmd, authLogoutCmd, authStatusCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+func authLoginHandler(provider string) error {
+	if err := checkAuthProvider(provider); err != nil {
+		return err
+	}
+	store, err := credential.GetStore(authArgs.Store)
+	if err != nil {
+		return err
+	}
+
+	key, err := readApiKey(provider)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("api key 不能为空")
+	}
+
+	if err := store.Set(provider, key); err != nil {
+		return err
+	}
+	fmt.Printf("已保存 %s api key 到 %s: %s\n", provider, store.Name(), credential.Mask(key))
+	return nil
+}
+
+func authLogoutHandler(provider string) error {
+	if err := checkAuthProvider(provider); err != nil {
+		return err
+	}
+	for _, store := range credential.Stores() {
+		if !store.Available() {
+			continue
+		}
+		if err := store.Delete(provider); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("已删除 %s api key\n", provider)
+	return nil
+}
+
+func authStatusHandler() error {
+	for _, provider := range credential.Providers {
+		if env, v := credential.LookupEnv(provider); v != "" {
+			fmt.Printf("%s: %s (环境变量 %s)\n", provider, credential.Mask(v), env)
+			continue
+		}
+		key, source, err := credential.Lookup(provider)
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			fmt.Printf("%s: 未配置\n", provider)
+			continue
+		}
+		fmt.Printf("%s: %s (%s)\n", provider, credential.Mask(key), source)
+	}
+	return nil
+}
+
+// checkAuthProvider 检查提供方是否需要 api key
+func checkAuthProvider(provider string) error {
+	for _, p := range credential.Providers {
+		if p == provider {
+			return nil
+		}
+	}
+	return fmt.Errorf("提供方 %q 不需要 api key，可选值: %s", provider, strings.Join(credential.Providers, ", "))
+}
+
+// readApiKey 读取 api key，终端中不回显输入，否则从管道读取一行
+func readApiKey(provider string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Printf("请输入 %s api key: ", provider)
+		key, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("读取输入失败: %w", err)
+		}
+		return strings.TrimSpace(string(key)), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("读取输入失败: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// apiKeyFlagUsage 生成 api key 参数的帮助信息
+func apiKeyFlagUsage(provider string) string {
+	return fmt.Sprintf("%s api key（不推荐，会暴露在进程列表和 shell 历史中，建议使用 go-cli auth login %s）", provider, provider)
+}
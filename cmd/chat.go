@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"github.com/MenciusCheng/go-cli/util/credential"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var chatArgs = struct {
+	FilePath           string
+	SelectionStartLine string
+	SelectionEndLine   string
+	DeepseekApiKey     string
+	QwenApiKey         string
+	Provider           string
+	Model              string
+	BaseURL            string
+	SessionID          string
+	Format             string
+}{}
+
+var chatCmd = &cobra.Command{
+	Use:   "chat [prompt]",
+	Short: "与大模型进行多轮对话",
+	Long: `进入终端交互式对话，保留对话历史，回答实时流式输出。
+对话中可以使用斜杠命令：/file 附加文件，/clear 清空历史，/model 切换模型，/save 保存对话，/help 查看帮助。`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return chatHandler(strings.Join(args, " "))
+	},
+}
+
+func init() {
+	chatCmd.Flags().StringVar(&chatArgs.FilePath, "filePath", "", "附加到第一条消息的文件路径")
+	chatCmd.Flags().StringVar(&chatArgs.SelectionStartLine, "selectionStartLine", "", "选择开始行号，只附加选中的行")
+	chatCmd.Flags().StringVar(&chatArgs.SelectionEndLine, "selectionEndLine", "", "选择结束行号")
+	chatCmd.Flags().StringVar(&chatArgs.DeepseekApiKey, "deepseekApiKey", "", apiKeyFlagUsage("deepseek"))
+	chatCmd.Flags().StringVar(&chatArgs.QwenApiKey, "qwenApiKey", "", apiKeyFlagUsage("qwen"))
+	chatCmd.Flags().StringVar(&chatArgs.Provider, "provider", "", providerFlagUsage())
+	chatCmd.Flags().StringVar(&chatArgs.Model, "model", "", modelFlagUsage())
+	chatCmd.Flags().StringVar(&chatArgs.BaseURL, "baseURL", "", "自定义服务地址，如 http://localhost:11434/v1")
+	chatCmd.Flags().StringVar(&chatArgs.Format, "format", "", formatFlagUsage())
+	chatCmd.Flags().StringVar(&chatArgs.SessionID, "session", "", "继续指定编号的会话，last 表示最近的会话")
+
+	rootCmd.AddCommand(chatCmd)
+}
+
+func chatHandler(prompt string) error {
+	e := &strategy.Event{
+		Prompt:             prompt,
+		FilePath:           chatArgs.FilePath,
+		SelectionStartLine: parseIntOrDefault(chatArgs.SelectionStartLine, 0),
+		SelectionEndLine:   parseIntOrDefault(chatArgs.SelectionEndLine, 0),
+		DeepseekApiKey:     credential.Secret(chatArgs.DeepseekApiKey),
+		QwenApiKey:         credential.Secret(chatArgs.QwenApiKey),
+		Provider:           chatArgs.Provider,
+		Model:              chatArgs.Model,
+		BaseURL:            chatArgs.BaseURL,
+		TabSize:            strategy.DefaultTabSize,
+		Interactive:        true,
+		SessionID:          chatArgs.SessionID,
+		Format:             chatArgs.Format,
+	}
+
+	if err := applyConfig(e); err != nil {
+		return err
+	}
+
+	return newStrategyManager(chatStrategies()).HandleEvent(e)
+}
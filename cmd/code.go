@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/credential"
+	"github.com/MenciusCheng/go-cli/util/openai"
 	"github.com/MenciusCheng/go-cli/util/strategy"
 	"github.com/MenciusCheng/go-cli/util/strategy/code_strategy"
 	"github.com/spf13/cobra"
 	"strconv"
+	"strings"
 )
 
 var codeArgs = struct {
@@ -14,10 +19,28 @@ var codeArgs = struct {
 	SelectionEndLine     string
 	SelectionStartColumn string
 	SelectionEndColumn   string
+	LineNumber           string
+	ColumnNumber         string
 	SelectedText         string
 	FileText             string
+	Language             string
 	DeepseekApiKey       string
 	QwenApiKey           string
+	Provider             string
+	Model                string
+	BaseURL              string
+	TabSize              int
+	Preview              bool
+	DryRun               bool
+	Validate             bool
+	Build                bool
+	Vet                  bool
+	RepairRounds         int
+	ResponseFormat       string
+	Format               string
+	OutputMode           string
+	Strategy             string
+	Explain              bool
 }{}
 
 var codeCmd = &cobra.Command{
@@ -42,10 +65,31 @@ func init() {
 	codeCmd.Flags().StringVar(&codeArgs.SelectionEndLine, "selectionEndLine", "", "选择结束行号")
 	codeCmd.Flags().StringVar(&codeArgs.SelectionStartColumn, "selectionStartColumn", "", "选择开始列号")
 	codeCmd.Flags().StringVar(&codeArgs.SelectionEndColumn, "selectionEndColumn", "", "选择结束列号")
+	codeCmd.Flags().StringVar(&codeArgs.LineNumber, "lineNumber", "", "光标所在行号，未选中代码时在光标处插入补全")
+	codeCmd.Flags().StringVar(&codeArgs.ColumnNumber, "columnNumber", "", "光标所在列号")
 	codeCmd.Flags().StringVar(&codeArgs.SelectedText, "selectedText", "", "选中的文本内容")
 	codeCmd.Flags().StringVar(&codeArgs.FileText, "fileText", "", "完整文件文本内容")
-	codeCmd.Flags().StringVar(&codeArgs.DeepseekApiKey, "deepseekApiKey", "", "deepseek api key")
-	codeCmd.Flags().StringVar(&codeArgs.QwenApiKey, "qwenApiKey", "", "qwen api key")
+	codeCmd.Flags().StringVar(&codeArgs.Language, "language", "", "文件语言，如 go、sql、shell（为空时根据 modeline、扩展名和 shebang 自动识别）")
+	codeCmd.Flags().StringVar(&codeArgs.DeepseekApiKey, "deepseekApiKey", "", apiKeyFlagUsage("deepseek"))
+	codeCmd.Flags().StringVar(&codeArgs.QwenApiKey, "qwenApiKey", "", apiKeyFlagUsage("qwen"))
+	codeCmd.Flags().StringVar(&codeArgs.Provider, "provider", "", providerFlagUsage())
+	codeCmd.Flags().StringVar(&codeArgs.Model, "model", "", modelFlagUsage())
+	codeCmd.Flags().IntVar(&codeArgs.TabSize, "tabSize", strategy.DefaultTabSize, "制表符宽度，用于换算 IDE 传入的列号")
+	codeCmd.Flags().StringVar(&codeArgs.BaseURL, "baseURL", "", "自定义服务地址，如 http://localhost:11434/v1")
+
+	codeCmd.Flags().BoolVar(&codeArgs.Preview, "preview", false, "写入文件前展示差异，确认后再写入")
+	codeCmd.Flags().BoolVar(&codeArgs.Preview, "interactive", false, "同 --preview")
+	codeCmd.Flags().BoolVar(&codeArgs.DryRun, "dry-run", false, "只展示差异，不写入文件")
+	codeCmd.Flags().BoolVar(&codeArgs.Validate, "validate", false, "写入前校验 Go 代码语法并使用 gofmt 格式化")
+	codeCmd.Flags().BoolVar(&codeArgs.Build, "build", false, "写入前执行 go build 校验（包含 --validate）")
+	codeCmd.Flags().BoolVar(&codeArgs.Vet, "vet", false, "写入前执行 go vet 校验（包含 --validate）")
+	codeCmd.Flags().StringVar(&codeArgs.ResponseFormat, "responseFormat", "", fmt.Sprintf("大模型返回修改的格式，可选值: %s（默认 %s）", strings.Join(rule.ResponseFormats, ", "), rule.FormatReplace))
+	codeCmd.Flags().StringVar(&codeArgs.Format, "format", strategy.FormatRaw,
+		fmt.Sprintf("回答的输出格式，可选值: %s（回答通常是源代码，默认原样输出）", strings.Join(strategy.Formats, ", ")))
+	codeCmd.Flags().StringVar(&codeArgs.OutputMode, "output", "", outputFlagUsage())
+	codeCmd.Flags().StringVar(&codeArgs.Strategy, "strategy", "", strategyFlagUsage(codeStrategies()))
+	codeCmd.Flags().BoolVar(&codeArgs.Explain, "explain", false, "只输出每个策略匹配或不匹配的原因，不执行策略")
+	codeCmd.Flags().IntVar(&codeArgs.RepairRounds, "repair", code_strategy.DefaultRepairRounds, "校验失败时将错误反馈给大模型修复的最大轮数")
 
 	rootCmd.AddCommand(codeCmd)
 }
@@ -59,19 +103,35 @@ func codeHandler(prompt string) error {
 		SelectionEndLine:     parseIntOrDefault(codeArgs.SelectionEndLine, 0),
 		SelectionStartColumn: parseIntOrDefault(codeArgs.SelectionStartColumn, 0),
 		SelectionEndColumn:   parseIntOrDefault(codeArgs.SelectionEndColumn, 0),
+		CaretLine:            parseIntOrDefault(codeArgs.LineNumber, 0),
+		CaretColumn:          parseIntOrDefault(codeArgs.ColumnNumber, 0),
 		SelectedText:         codeArgs.SelectedText,
 		FileText:             codeArgs.FileText,
-		DeepseekApiKey:       codeArgs.DeepseekApiKey,
-		QwenApiKey:           codeArgs.QwenApiKey,
+		Language:             codeArgs.Language,
+		DeepseekApiKey:       credential.Secret(codeArgs.DeepseekApiKey),
+		QwenApiKey:           credential.Secret(codeArgs.QwenApiKey),
+		Provider:             codeArgs.Provider,
+		Model:                codeArgs.Model,
+		BaseURL:              codeArgs.BaseURL,
+		TabSize:              codeArgs.TabSize,
+		Preview:              codeArgs.Preview,
+		DryRun:               codeArgs.DryRun,
+		Validate:             codeArgs.Validate,
+		Build:                codeArgs.Build,
+		Vet:                  codeArgs.Vet,
+		RepairRounds:         codeArgs.RepairRounds,
+		ResponseFormat:       codeArgs.ResponseFormat,
+		Format:               codeArgs.Format,
+		OutputMode:           codeArgs.OutputMode,
+		Strategy:             codeArgs.Strategy,
+		Explain:              codeArgs.Explain,
 	}
 
-	sm := strategy.NewStrategyManager()
-	sm.RegisterStrategies(
-		code_strategy.NewCodeStrategy(),
-		strategy.NewEchoStrategy(),
-	)
+	if err := applyConfig(e); err != nil {
+		return err
+	}
 
-	return sm.HandleEvent(e)
+	return newStrategyManager(withRuleStrategies("code", codeStrategies())).HandleEvent(e)
 }
 
 // parseIntOrDefault 解析字符串为整数，如果为空或解析失败则返回默认值
@@ -84,3 +144,21 @@ func parseIntOrDefault(s string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// providerFlagUsage 生成 --provider 参数的帮助信息
+func providerFlagUsage() string {
+	return fmt.Sprintf("大模型提供方，可选值: %s（为空时根据已配置的 api key 自动选择，默认 %s）",
+		strings.Join(openai.ProviderNames(), ", "), strategy.DefaultProvider)
+}
+
+// modelFlagUsage 生成 --model 参数的帮助信息
+func modelFlagUsage() string {
+	var parts []string
+	for _, name := range openai.ProviderNames() {
+		p, _ := openai.GetProvider(name)
+		if models := openai.ModelNames(p); len(models) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", name, strings.Join(models, "/")))
+		}
+	}
+	return fmt.Sprintf("模型名称，为空时使用提供方默认模型（%s）", strings.Join(parts, "; "))
+}
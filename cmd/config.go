@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/config"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var configArgs = struct {
+	Project bool
+}{}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "管理配置文件",
+	Long: `管理 go-cli 配置文件。
+
+配置优先级（从低到高）：用户配置 ~/.config/go-cli/config.yaml < 项目配置 .go-cli.yaml < profile < 环境变量 GO_CLI_* < 命令行参数。
+使用 --profile 指定命名 profile，读写配置时同样作用于该 profile。
+项目配置中的 hook 和 baseURL 只有执行 go-cli config trust 信任后才会生效，文件内容变化后需要重新信任。`,
+}
+
+var configTrustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "信任当前项目的 .go-cli.yaml，允许其中的 hook 和 baseURL 生效",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configTrustHandler()
+	},
+}
+
+var configUntrustCmd = &cobra.Command{
+	Use:   "untrust",
+	Short: "取消信任当前项目的 .go-cli.yaml",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configUntrustHandler()
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "查看配置项的生效值",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configGetHandler(args[0])
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "设置配置项，value 为空字符串时删除该配置项",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configSetHandler(args[0], args[1])
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出所有配置项的生效值及来源",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configListHandler()
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "显示配置文件路径",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configPathHandler()
+	},
+}
+
+func init() {
+	configSetCmd.Flags().BoolVar(&configArgs.Project, "project", false, "写入当前项目的 .go-cli.yaml")
+
+	configCmd.AddCommand(configGetCmd, configSetCmd, configListCmd, configPathCmd, configTrustCmd, configUntrustCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func configGetHandler(key string) error {
+	r, err := config.Resolve(rootArgs.Profile)
+	if err != nil {
+		return err
+	}
+	if key == "profile" {
+		fmt.Println(r.Profile)
+		return nil
+	}
+	if err := config.CheckKey(key); err != nil {
+		return err
+	}
+	fmt.Println(r.Get(key))
+	return nil
+}
+
+func configSetHandler(key, value string) error {
+	path := config.UserPath()
+	trusted := false
+	if configArgs.Project {
+		if path = config.ProjectPath(); path == "" {
+			path = config.ProjectFileName
+		}
+		trusted = config.IsTrusted(path)
+	}
+	c, err := config.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if key == "profile" {
+		// 设置默认 profile
+		c.Profile = value
+	} else {
+		settings := &c.Settings
+		if rootArgs.Profile != "" {
+			if c.Profiles == nil {
+				c.Profiles = make(map[string]*config.Settings)
+			}
+			if c.Profiles[rootArgs.Profile] == nil {
+				c.Profiles[rootArgs.Profile] = &config.Settings{}
+			}
+			settings = c.Profiles[rootArgs.Profile]
+		}
+		if key == "responseFormat" && value != "" {
+			if err := rule.CheckResponseFormat(value); err != nil {
+				return errcode.Wrap(errcode.InvalidArgument, err)
+			}
+		}
+		if err := settings.Set(key, value); err != nil {
+			return err
+		}
+	}
+
+	if err := config.SaveFile(path, c); err != nil {
+		return err
+	}
+	if trusted {
+		// 通过 config set 修改已信任的项目配置时保持信任
+		if err := config.Trust(path); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("已更新配置文件: %s\n", path)
+	return nil
+}
+
+func configListHandler() error {
+	r, err := config.Resolve(rootArgs.Profile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("profile = %s\n", r.Profile)
+	for _, key := range config.Keys {
+		value := r.Get(key)
+		if value == "" {
+			fmt.Printf("%s = \n", key)
+			continue
+		}
+		fmt.Printf("%s = %s\t(%s)\n", key, value, r.Sources[key])
+	}
+	if len(r.ProfileNames) > 0 {
+		fmt.Printf("可用 profile: %s\n", strings.Join(r.ProfileNames, ", "))
+	}
+	for _, h := range r.Hooks {
+		files := ""
+		if h.Files != "" {
+			files = fmt.Sprintf(" [%s]", h.Files)
+		}
+		fmt.Printf("hook %s%s: %s\n", h.When, files, h.Run)
+	}
+	if len(r.Ignored) > 0 {
+		fmt.Printf("项目配置 %s 未被信任，已忽略其中的 %s（执行 go-cli config trust 信任）\n", r.ProjectPath, strings.Join(r.Ignored, "、"))
+	}
+	return nil
+}
+
+func configTrustHandler() error {
+	path := config.ProjectPath()
+	if path == "" {
+		return errcode.Wrap(errcode.InvalidArgument, fmt.Errorf("未找到项目配置 %s", config.ProjectFileName))
+	}
+	c, err := config.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	if c.BaseURL != "" {
+		fmt.Printf("baseURL: %s\n", c.BaseURL)
+	}
+	for _, name := range c.ProfileNames() {
+		if p := c.Profiles[name]; p != nil && p.BaseURL != "" {
+			fmt.Printf("baseURL (profile %s): %s\n", name, p.BaseURL)
+		}
+	}
+	for _, h := range c.Hooks {
+		fmt.Printf("hook %s: %s\n", h.When, h.Run)
+	}
+	if err := config.Trust(path); err != nil {
+		return err
+	}
+	fmt.Printf("已信任项目配置: %s\n", path)
+	return nil
+}
+
+func configUntrustHandler() error {
+	path := config.ProjectPath()
+	if path == "" {
+		return errcode.Wrap(errcode.InvalidArgument, fmt.Errorf("未找到项目配置 %s", config.ProjectFileName))
+	}
+	if err := config.Untrust(path); err != nil {
+		return err
+	}
+	fmt.Printf("已取消信任项目配置: %s\n", path)
+	return nil
+}
+
+func configPathHandler() error {
+	fmt.Printf("用户配置: %s\n", config.UserPath())
+	if path := config.ProjectPath(); path != "" {
+		trusted := "未信任"
+		if config.IsTrusted(path) {
+			trusted = "已信任"
+		}
+		fmt.Printf("项目配置: %s（%s）\n", path, trusted)
+	} else {
+		fmt.Printf("项目配置: 未找到 %s\n", config.ProjectFileName)
+	}
+	return nil
+}
+
+// applyConfig 使用配置文件和环境变量补全事件中未通过命令行指定的参数
+func applyConfig(e *strategy.Event) error {
+	r, err := config.Resolve(rootArgs.Profile)
+	if err != nil {
+		return err
+	}
+	if len(r.Ignored) > 0 {
+		logger.Warnf("项目配置 %s 未被信任，已忽略其中的 %s，确认内容安全后执行 go-cli config trust\n", r.ProjectPath, strings.Join(r.Ignored, "、"))
+	}
+	if e.Provider == "" {
+		e.Provider = r.Provider
+	}
+	if e.Model == "" {
+		e.Model = r.Model
+	}
+	if e.BaseURL == "" {
+		e.BaseURL = r.BaseURL
+	}
+	if e.Temperature == nil {
+		e.Temperature = r.Temperature
+	}
+	if e.MaxContextSize == 0 && r.MaxContextSize != nil {
+		e.MaxContextSize = *r.MaxContextSize
+	}
+	if e.ResponseFormat == "" {
+		e.ResponseFormat = r.ResponseFormat
+	}
+	e.Hooks = r.Hooks
+	return nil
+}
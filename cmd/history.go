@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/journal"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "查看历史记录",
+}
+
+var historyEditsCmd = &cobra.Command{
+	Use:   "edits",
+	Short: "查看 code 命令的文件修改记录",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return historyEditsHandler()
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyEditsCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+func historyEditsHandler() error {
+	j := journal.New()
+	entries, err := j.List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("暂无修改记录")
+		return nil
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		status := ""
+		if e.Created {
+			status += " [新建]"
+		}
+		if e.Undone {
+			status += " [已撤销]"
+		}
+		fmt.Printf("#%d %s %s%s\n", e.ID, e.Time.Format("2006-01-02 15:04:05"), e.Path, status)
+		if e.Large {
+			fmt.Printf("    模型: %s  内容过大，未保存，无法撤销\n", e.Model)
+		} else {
+			fmt.Printf("    模型: %s  %s -> %s\n", e.Model, journal.Hash(e.Original), journal.Hash(e.New))
+		}
+		if prompt := strings.TrimSpace(e.Prompt); prompt != "" {
+			fmt.Printf("    提示词: %s\n", truncate(prompt, 80))
+		}
+	}
+	fmt.Printf("\n记录文件: %s\n", j.Path())
+	return nil
+}
+
+// truncate 截断过长的单行文本
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/MenciusCheng/go-cli/util/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -14,17 +17,61 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initLogger()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+	if err != nil {
+		logger.Debugf("执行失败: %v\n", err)
+	}
+	logger.Close()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// rootArgs 全局参数
+var rootArgs = struct {
+	Profile  string
+	Verbose  bool
+	Quiet    bool
+	LogLevel string
+	LogFile  string
+}{}
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(&rootArgs.Profile, "profile", "", "使用配置文件中的命名 profile")
+	rootCmd.PersistentFlags().BoolVarP(&rootArgs.Verbose, "verbose", "v", false, "输出调试信息，包括发送给大模型的提示词，同 --log-level debug")
+	rootCmd.PersistentFlags().BoolVarP(&rootArgs.Quiet, "quiet", "q", false, "只输出回答和结果，不输出提示信息，同 --log-level warn")
+	rootCmd.PersistentFlags().StringVar(&rootArgs.LogLevel, "log-level", "", fmt.Sprintf("标准错误中的日志级别，可选值: %s（默认 info）", strings.Join(logger.LevelNames, ", ")))
+	rootCmd.PersistentFlags().StringVar(&rootArgs.LogFile, "log-file", "", "将 debug 级别的日志追加写入文件，便于反馈问题")
+}
 
+// initLogger 根据全局参数设置日志级别和日志文件，提示信息和调试信息输出到标准错误
+func initLogger() error {
+	level := logger.LevelInfo
+	switch {
+	case rootArgs.LogLevel != "":
+		l, err := logger.ParseLevel(rootArgs.LogLevel)
+		if err != nil {
+			return err
+		}
+		level = l
+	case rootArgs.Verbose && rootArgs.Quiet:
+		return fmt.Errorf("-v 和 -q 不能同时使用")
+	case rootArgs.Verbose:
+		level = logger.LevelDebug
+	case rootArgs.Quiet:
+		level = logger.LevelWarn
+	}
+	logger.SetLevel(level)
+	if rootArgs.LogFile != "" {
+		return logger.OpenFile(rootArgs.LogFile)
+	}
+	return nil
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/config"
+	"github.com/spf13/cobra"
+)
+
+var rulesArgs struct {
+	Builtin bool
+}
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules [name]",
+	Short: "查看规则模板",
+	Long: `列出规则模板及实际使用的文件，指定名称时输出模板内容。
+
+在项目规则目录 .go-cli/rules 或用户规则目录 ~/.config/go-cli/rules 中放入同名文件即可覆盖内置模板，
+项目规则目录优先。文件有语言时优先使用该语言的模板，如 Go 文件优先使用 code_rule.go.tmpl。模板开头可以用 --- 包裹 yaml front matter，声明 system、temperature、model、maxTokens、responseFormat。
+
+示例：
+  go-cli rules
+  go-cli rules code_rule.tmpl --builtin > .go-cli/rules/code_rule.tmpl`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return rulesListHandler()
+		}
+		return rulesShowHandler(args[0], rulesArgs.Builtin)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.Flags().BoolVar(&rulesArgs.Builtin, "builtin", false, "输出内置模板的内容，忽略规则目录中的同名文件")
+}
+
+func rulesListHandler() error {
+	fmt.Printf("用户规则目录: %s\n", config.UserRulesDir())
+	if dir := config.ProjectRulesDir(); dir != "" {
+		fmt.Printf("项目规则目录: %s\n", dir)
+	}
+	fmt.Println()
+	for _, name := range rule.AllTemplateNames() {
+		t, err := rule.LoadTemplate(name, "")
+		if err != nil {
+			fmt.Printf("  %-30s 错误: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("  %-30s %s\n", name, t.Source())
+	}
+	return nil
+}
+
+func rulesShowHandler(name string, builtin bool) error {
+	if builtin {
+		content, err := rule.BuiltinTemplate(name)
+		if err != nil {
+			return err
+		}
+		fmt.Print(content)
+		return nil
+	}
+	if _, err := rule.LoadTemplate(name, ""); err != nil {
+		return err
+	}
+	content, _, err := rule.ReadTemplate(name)
+	if err != nil {
+		return err
+	}
+	fmt.Print(content)
+	return nil
+}
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/session"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var sessionsArgs = struct {
+	FilePath string
+	Limit    int
+	Format   string
+	Output   string
+	Provider string
+	Model    string
+	BaseURL  string
+}{}
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "管理 ask 和 chat 命令保存的会话",
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出会话，按最后更新时间倒序排列",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sessionsListHandler()
+	},
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "查看会话的完整问答",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sessionsShowHandler(args[0])
+	},
+}
+
+var sessionsResumeCmd = &cobra.Command{
+	Use:   "resume [id] [prompt]",
+	Short: "继续会话，进入多轮对话（默认继续最近的会话）",
+	Args:  cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, prompt := session.Last, ""
+		if len(args) > 0 {
+			id = args[0]
+		}
+		if len(args) > 1 {
+			prompt = args[1]
+		}
+		return sessionsResumeHandler(id, prompt)
+	},
+}
+
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete <id>...",
+	Short: "删除会话",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sessionsDeleteHandler(args)
+	},
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <id>",
+	Short: "导出会话为 markdown 或 json",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sessionsExportHandler(args[0])
+	},
+}
+
+var sessionsSearchCmd = &cobra.Command{
+	Use:   "search <keyword>",
+	Short: "在问题和回答中搜索关键字",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sessionsSearchHandler(strings.Join(args, " "))
+	},
+}
+
+func init() {
+	sessionsListCmd.Flags().StringVar(&sessionsArgs.FilePath, "filePath", "", "只列出与该文件相关的会话")
+	sessionsListCmd.Flags().IntVar(&sessionsArgs.Limit, "limit", 20, "最多列出的会话数，0 表示不限制")
+	sessionsExportCmd.Flags().StringVar(&sessionsArgs.Format, "format", "markdown", "导出格式，可选值: markdown, json")
+	sessionsExportCmd.Flags().StringVarP(&sessionsArgs.Output, "output", "o", "", "导出到文件，为空时输出到标准输出")
+	sessionsResumeCmd.Flags().StringVar(&sessionsArgs.Provider, "provider", "", "大模型提供方，为空时使用会话最近一次的提供方")
+	sessionsResumeCmd.Flags().StringVar(&sessionsArgs.Model, "model", "", "模型名称，为空时使用会话最近一次的模型")
+	sessionsResumeCmd.Flags().StringVar(&sessionsArgs.BaseURL, "baseURL", "", "自定义服务地址，如 http://localhost:11434/v1")
+
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsShowCmd, sessionsResumeCmd, sessionsDeleteCmd, sessionsExportCmd, sessionsSearchCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+func sessionsListHandler() error {
+	store := session.New()
+	sessions, err := store.List()
+	if err != nil {
+		return err
+	}
+	if sessionsArgs.FilePath != "" {
+		sessions = filterSessionsByFile(sessions, sessionsArgs.FilePath)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("暂无会话记录")
+		return nil
+	}
+	if sessionsArgs.Limit > 0 && len(sessions) > sessionsArgs.Limit {
+		sessions = sessions[:sessionsArgs.Limit]
+	}
+	for _, s := range sessions {
+		fmt.Printf("%s %s %d 轮  %s\n", s.ID, s.Updated().Format("2006-01-02 15:04:05"), len(s.Turns), truncate(s.Title(), 60))
+		if path := s.FilePath(); path != "" {
+			fmt.Printf("    文件: %s\n", path)
+		}
+	}
+	fmt.Printf("\n会话目录: %s\n", store.Dir())
+	return nil
+}
+
+// filterSessionsByFile 筛选与文件相关的会话，按绝对路径比较
+func filterSessionsByFile(sessions []*session.Session, filePath string) []*session.Session {
+	want := absPath(filePath)
+	var result []*session.Session
+	for _, s := range sessions {
+		for _, t := range s.Turns {
+			if t.FilePath != "" && absPath(t.FilePath) == want {
+				result = append(result, s)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func sessionsShowHandler(id string) error {
+	s, err := session.New().Load(id)
+	if err != nil {
+		return err
+	}
+	usage := s.Usage()
+	fmt.Printf("会话 %s，共 %d 轮问答，累计 %d tokens\n", s.ID, len(s.Turns), usage.TotalTokens)
+	for i, t := range s.Turns {
+		fmt.Printf("\n=== 第 %d 轮 %s 模型: %s tokens: %d ===\n", i+1, t.Time.Format("2006-01-02 15:04:05"), t.Model, t.Usage.TotalTokens)
+		if t.FilePath != "" {
+			fmt.Printf("文件: %s\n", t.FilePath)
+		}
+		fmt.Printf("问题: %s\n\n", t.Prompt)
+		fmt.Println(strings.TrimSpace(t.Answer))
+	}
+	return nil
+}
+
+func sessionsResumeHandler(id, prompt string) error {
+	s, err := session.New().Load(id)
+	if err != nil {
+		return err
+	}
+	e := &strategy.Event{
+		Prompt:      prompt,
+		Provider:    sessionsArgs.Provider,
+		Model:       sessionsArgs.Model,
+		BaseURL:     sessionsArgs.BaseURL,
+		TabSize:     strategy.DefaultTabSize,
+		Interactive: true,
+		SessionID:   s.ID,
+	}
+	// 默认沿用会话最近一次使用的模型和服务地址
+	var last *session.Turn
+	if n := len(s.Turns); n > 0 && e.Provider == "" && e.Model == "" && e.BaseURL == "" {
+		last = &s.Turns[n-1]
+		e.Provider, e.Model = last.Provider, last.Model
+	}
+
+	if err := applyConfig(e); err != nil {
+		return err
+	}
+	if last != nil {
+		// 会话记录的服务地址优先于配置文件，为空表示提供方的默认地址
+		e.BaseURL = last.BaseURL
+	}
+
+	return newStrategyManager(chatStrategies()).HandleEvent(e)
+}
+
+func sessionsDeleteHandler(ids []string) error {
+	store := session.New()
+	for _, id := range ids {
+		deleted, err := store.Delete(id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已删除会话 %s\n", deleted)
+	}
+	return nil
+}
+
+func sessionsExportHandler(id string) error {
+	s, err := session.New().Load(id)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	switch sessionsArgs.Format {
+	case "markdown", "md":
+		data = []byte(s.Markdown())
+	case "json":
+		if data, err = json.MarshalIndent(s, "", "  "); err != nil {
+			return fmt.Errorf("序列化会话失败: %w", err)
+		}
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("不支持的导出格式 %q，可选值: markdown, json", sessionsArgs.Format)
+	}
+	if sessionsArgs.Output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(sessionsArgs.Output, data, 0644); err != nil {
+		return fmt.Errorf("导出会话失败: %w", err)
+	}
+	fmt.Printf("已导出会话 %s 到 %s\n", s.ID, sessionsArgs.Output)
+	return nil
+}
+
+func sessionsSearchHandler(keyword string) error {
+	matches, err := session.New().Search(keyword)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		fmt.Printf("未找到包含 %q 的问答\n", keyword)
+		return nil
+	}
+	for _, m := range matches {
+		t := m.Session.Turns[m.Turn]
+		fmt.Printf("%s 第 %d 轮 %s\n", m.Session.ID, m.Turn+1, t.Time.Format("2006-01-02 15:04:05"))
+		fmt.Printf("    问题: %s\n", truncate(t.Prompt, 80))
+		fmt.Printf("    回答: %s\n", snippet(t.Answer, keyword, 80))
+	}
+	return nil
+}
+
+// snippet 截取关键字附近的文本，找不到关键字时截取开头
+func snippet(text, keyword string, n int) string {
+	runes := []rune(strings.ReplaceAll(text, "\n", " "))
+	start := 0
+	if i := indexFold(runes, keyword); i >= 0 {
+		start = max(i-n/4, 0)
+	}
+	start = min(start, len(runes))
+	end := min(start+n, len(runes))
+	result := string(runes[start:end])
+	if start > 0 {
+		result = "..." + result
+	}
+	if end < len(runes) {
+		result += "..."
+	}
+	return result
+}
+
+// indexFold 在 runes 中不区分大小写地查找 keyword，返回按 rune 计算的位置，未找到时返回 -1
+// 逐个位置比较原文，避免大小写转换改变字符长度后位置错位
+func indexFold(runes []rune, keyword string) int {
+	k := len([]rune(keyword))
+	for i := 0; i+k <= len(runes); i++ {
+		if strings.EqualFold(string(runes[i:i+k]), keyword) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"github.com/MenciusCheng/go-cli/util/strategy/ask_strategy"
+	"github.com/MenciusCheng/go-cli/util/strategy/code_strategy"
+	"github.com/MenciusCheng/go-cli/util/strategy/rule_strategy"
+	"github.com/spf13/cobra"
+	"strings"
+	"sync"
+)
+
+// commandStrategies 各命令注册的策略，按注册顺序排列
+var commandStrategies = []struct {
+	Command    string
+	Strategies func() []strategy.Strategy
+}{
+	{"ask", askStrategies},
+	{"code", codeStrategies},
+	{"chat", chatStrategies},
+}
+
+func askStrategies() []strategy.Strategy {
+	return []strategy.Strategy{
+		ask_strategy.NewChatStrategy(),
+		ask_strategy.NewAskCodeStrategy(),
+		ask_strategy.NewAskAnyStrategy(),
+		strategy.NewEchoStrategy(),
+	}
+}
+
+func codeStrategies() []strategy.Strategy {
+	return []strategy.Strategy{
+		code_strategy.NewCodeStrategy(),
+		code_strategy.NewInsertStrategy(),
+		strategy.NewEchoStrategy(),
+	}
+}
+
+func chatStrategies() []strategy.Strategy {
+	return []strategy.Strategy{
+		ask_strategy.NewChatStrategy(),
+	}
+}
+
+// loadSpecs 加载规则目录中的声明式策略，每个进程只加载一次，格式错误的规则文件只输出警告
+var loadSpecs = sync.OnceValue(func() []*rule.Spec {
+	specs, err := rule.LoadSpecs()
+	if err != nil {
+		logger.Warnf("%v\n", err)
+	}
+	return specs
+})
+
+// ruleStrategies 规则目录中属于该命令的声明式策略
+func ruleStrategies(command string) []strategy.Strategy {
+	specs := loadSpecs()
+	var list []strategy.Strategy
+	for _, spec := range specs {
+		if spec.Command == command {
+			list = append(list, rule_strategy.NewRuleStrategy(spec))
+		}
+	}
+	return list
+}
+
+// withRuleStrategies 声明式策略注册在内置策略之前，得分相同时优先选择
+func withRuleStrategies(command string, builtin []strategy.Strategy) []strategy.Strategy {
+	return append(ruleStrategies(command), builtin...)
+}
+
+// newStrategyManager 创建策略管理器并注册策略
+func newStrategyManager(strategies []strategy.Strategy) *strategy.StrategyManager {
+	sm := strategy.NewStrategyManager()
+	sm.RegisterStrategies(strategies...)
+	return sm
+}
+
+var strategiesCmd = &cobra.Command{
+	Use:   "strategies [command]",
+	Short: "列出各命令注册的策略",
+	Long: `列出 ask、code、chat 命令注册的策略及说明。
+
+规则目录（~/.config/go-cli/rules、项目的 .go-cli/rules）中声明的策略列在内置策略之前。
+
+未指定 --strategy 时，从能处理请求的策略中选择匹配得分最高的策略，得分相同时按注册顺序选择。
+使用 --strategy NAME 指定策略，使用 --explain 查看每个策略匹配或不匹配的原因。`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var command string
+		if len(args) > 0 {
+			command = args[0]
+		}
+		return strategiesHandler(command)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(strategiesCmd)
+}
+
+func strategiesHandler(command string) error {
+	found := false
+	for _, c := range commandStrategies {
+		if command != "" && c.Command != command {
+			continue
+		}
+		if found {
+			fmt.Println()
+		}
+		found = true
+		fmt.Printf("%s:\n", c.Command)
+		for _, s := range withRuleStrategies(c.Command, c.Strategies()) {
+			fmt.Printf("  %-16s %s\n", s.GetName(), s.Description())
+		}
+	}
+	if !found {
+		names := make([]string, 0, len(commandStrategies))
+		for _, c := range commandStrategies {
+			names = append(names, c.Command)
+		}
+		return fmt.Errorf("未知的命令 %q，可选值: %s", command, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// strategyFlagUsage 生成 --strategy 参数的帮助信息
+func strategyFlagUsage(strategies []strategy.Strategy) string {
+	names := make([]string, 0, len(strategies))
+	for _, s := range strategies {
+		names = append(names, s.GetName())
+	}
+	return fmt.Sprintf("指定使用的策略，可省略 Strategy 后缀，可选值: %s 及规则目录中声明的策略（为空时自动选择）", strings.Join(names, ", "))
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/journal"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [n]",
+	Short: "撤销最近 n 次代码修改",
+	Long:  `撤销 code 命令最近 n 次对文件的修改（默认 1 次）。如果文件在修改后又被变更，会拒绝撤销以免覆盖新的修改。`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := 1
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n <= 0 {
+				return fmt.Errorf("n 必须是正整数: %s", args[0])
+			}
+		}
+		return undoHandler(n)
+	},
+}
+
+var redoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "重做最近一次被撤销的代码修改",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return redoHandler()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd, redoCmd)
+}
+
+func undoHandler(n int) error {
+	undone, err := journal.New().Undo(n)
+	for _, e := range undone {
+		fmt.Printf("已撤销 #%d: %s\n", e.ID, e.Path)
+	}
+	return err
+}
+
+func redoHandler() error {
+	e, err := journal.New().Redo()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已重做 #%d: %s\n", e.ID, e.Path)
+	return nil
+}
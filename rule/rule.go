@@ -1,9 +1,24 @@
 package rule
 
-import _ "embed"
+import (
+	"fmt"
+	"strings"
+)
 
-//go:embed code_rule.tmpl
-var CodeRuleTemplate string
+// 代码补全的返回格式
+const (
+	FormatReplace       = "replace"        // 返回替换选中区域的完整代码
+	FormatSearchReplace = "search-replace" // 返回 SEARCH/REPLACE 修改块
+	FormatDiff          = "diff"           // 返回统一格式差异
+)
 
-//go:embed ask_rule.tmpl
-var AskRuleTemplate string
+// ResponseFormats 支持的代码补全返回格式
+var ResponseFormats = []string{FormatReplace, FormatSearchReplace, FormatDiff}
+
+// CheckResponseFormat 检查返回格式是否支持
+func CheckResponseFormat(format string) error {
+	if _, ok := CodeRuleTemplates[format]; !ok {
+		return fmt.Errorf("不支持的返回格式 %q，可选值: %s", format, strings.Join(ResponseFormats, ", "))
+	}
+	return nil
+}
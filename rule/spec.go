@@ -0,0 +1,224 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/config"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 声明式策略的输出方式
+const (
+	OutputPrint   = "print"    // 输出回答
+	OutputReplace = "replace"  // 用回答中的代码替换选中的代码，未选中时替换整个文件
+	OutputInsert  = "insert"   // 在光标处插入回答中的代码，未提供光标时插入到选中区域之后
+	OutputNewFile = "new-file" // 将回答中的代码写入新文件
+)
+
+// Outputs 支持的输出方式
+var Outputs = []string{OutputPrint, OutputReplace, OutputInsert, OutputNewFile}
+
+// SpecCommands 声明式策略支持的命令
+var SpecCommands = []string{"ask", "code"}
+
+// Spec 声明式策略，定义在规则目录下的 yaml 文件中
+type Spec struct {
+	Name         string    `yaml:"name"`         // 策略名称，默认为文件名
+	Description  string    `yaml:"description"`  // 策略说明
+	Command      string    `yaml:"command"`      // 所属命令：ask、code
+	Priority     int       `yaml:"priority"`     // 匹配得分，默认 60
+	Match        SpecMatch `yaml:"match"`        // 匹配条件，全部满足时才能处理
+	Template     string    `yaml:"template"`     // 提示词模板
+	TemplateFile string    `yaml:"templateFile"` // 提示词模板文件，相对于规则文件所在目录
+	Provider     string    `yaml:"provider"`     // 大模型提供方
+	Output       string    `yaml:"output"`       // 输出方式：print、replace、insert、new-file
+	NewFile      string    `yaml:"newFile"`      // 输出方式为 new-file 时的文件路径模板
+
+	// system、temperature、model、maxTokens，覆盖模板 front matter 中的同名字段
+	TemplateMeta `yaml:",inline"`
+
+	Path     string         `yaml:"-"` // 规则文件路径
+	promptRe *regexp.Regexp // 编译后的 match.prompt
+}
+
+// SpecMatch 声明式策略的匹配条件，未设置的条件不参与匹配
+type SpecMatch struct {
+	Files     stringList `yaml:"files"`     // 文件名匹配的 glob，如 *_test.go，可以是列表
+	Language  string     `yaml:"language"`  // 文件语言，如 go、sql
+	Prompt    string     `yaml:"prompt"`    // 提示词需要匹配的正则表达式
+	Selection *bool      `yaml:"selection"` // true 表示必须选中代码，false 表示必须未选中代码
+}
+
+// DefaultSpecPriority 声明式策略默认的匹配得分，高于内置的策略
+const DefaultSpecPriority = 60
+
+// PromptMatch 提示词是否匹配 match.prompt，未设置时始终匹配
+func (s *Spec) PromptMatch(prompt string) bool {
+	return s.promptRe == nil || s.promptRe.MatchString(prompt)
+}
+
+// stringList 可以写成单个字符串或字符串列表
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Dirs 规则目录，用户规则目录在前，项目规则目录在后
+func Dirs() []string {
+	dirs := []string{config.UserRulesDir()}
+	if dir := config.ProjectRulesDir(); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// LoadSpecs 加载规则目录下所有 .yaml、.yml 文件中的声明式策略
+// 项目规则中的同名策略覆盖用户规则，结果按名称排序
+// 格式错误的文件会被跳过，错误合并后返回
+func LoadSpecs() ([]*Spec, error) {
+	byName := make(map[string]*Spec)
+	var errs []error
+	for _, dir := range Dirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("读取规则目录失败: %w", err))
+			}
+			continue
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || ext != ".yaml" && ext != ".yml" {
+				continue
+			}
+			spec, err := LoadSpec(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			byName[strings.ToLower(spec.Name)] = spec
+		}
+	}
+
+	specs := make([]*Spec, 0, len(byName))
+	for _, spec := range byName {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs, errors.Join(errs...)
+}
+
+// LoadSpec 加载并校验一个声明式策略文件
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取规则文件失败: %w", err)
+	}
+	spec := &Spec{}
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("解析规则文件 %s 失败: %w", path, err)
+	}
+	spec.Path = path
+	if err := spec.init(); err != nil {
+		return nil, fmt.Errorf("规则文件 %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// init 补全默认值并校验
+func (s *Spec) init() error {
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(s.Path), filepath.Ext(s.Path))
+	}
+	if s.Priority == 0 {
+		s.Priority = DefaultSpecPriority
+	}
+	if s.Priority < 0 {
+		return fmt.Errorf("priority 不能为负数")
+	}
+
+	switch s.Command {
+	case "ask":
+		if s.Output == "" {
+			s.Output = OutputPrint
+		}
+	case "code":
+		if s.Output == "" {
+			s.Output = OutputReplace
+		}
+	default:
+		return fmt.Errorf("不支持的命令 %q，可选值: %s", s.Command, strings.Join(SpecCommands, ", "))
+	}
+	switch s.Output {
+	case OutputPrint, OutputReplace, OutputInsert:
+	case OutputNewFile:
+		if s.NewFile == "" {
+			return fmt.Errorf("output 为 %s 时必须设置 newFile", OutputNewFile)
+		}
+	default:
+		return fmt.Errorf("不支持的输出方式 %q，可选值: %s", s.Output, strings.Join(Outputs, ", "))
+	}
+
+	if err := s.TemplateMeta.validate(); err != nil {
+		return err
+	}
+	if s.ResponseFormat != "" {
+		return fmt.Errorf("不支持 responseFormat，请使用 output 指定输出方式")
+	}
+	switch {
+	case s.Template != "" && s.TemplateFile != "":
+		return fmt.Errorf("template 和 templateFile 只能设置一个")
+	case s.TemplateFile != "":
+		path := s.TemplateFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(s.Path), path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取模板文件失败: %w", err)
+		}
+		// 模板文件可以带 front matter，规则文件中的同名字段优先
+		t, err := ParseTemplate(filepath.Base(path), string(data))
+		if err != nil {
+			return fmt.Errorf("模板文件 %s: %w", path, err)
+		}
+		if t.ResponseFormat != "" {
+			return fmt.Errorf("模板文件 %s: 不支持 responseFormat，请使用 output 指定输出方式", path)
+		}
+		s.Template = t.Body
+		s.TemplateMeta = t.TemplateMeta.Merge(s.TemplateMeta)
+	case s.Template == "":
+		return fmt.Errorf("必须设置 template 或 templateFile")
+	}
+
+	for _, pattern := range s.Match.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("match.files 格式错误 %q: %w", pattern, err)
+		}
+	}
+	if s.Match.Prompt != "" {
+		re, err := regexp.Compile(s.Match.Prompt)
+		if err != nil {
+			return fmt.Errorf("match.prompt 不是合法的正则表达式: %w", err)
+		}
+		s.promptRe = re
+	}
+	return nil
+}
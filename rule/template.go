@@ -0,0 +1,288 @@
+package rule
+
+import (
+	"embed"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed *.tmpl
+var embedded embed.FS
+
+// 内置的规则模板，规则目录中的同名文件会覆盖内置模板
+const (
+	AskRule               = "ask_rule.tmpl"                 // 结合选中的代码提问
+	ChatRule              = "chat_rule.tmpl"                // 直接提问和交互式对话，只使用 front matter
+	CodeRule              = "code_rule.tmpl"                // 修改选中的代码，返回完整代码
+	CodeSearchReplaceRule = "code_rule_search_replace.tmpl" // 修改选中的代码，返回 SEARCH/REPLACE 修改块
+	CodeDiffRule          = "code_rule_diff.tmpl"           // 修改选中的代码，返回统一格式差异
+	InsertRule            = "insert_rule.tmpl"              // 在光标处插入代码
+	RepairRule            = "repair_rule.tmpl"              // 校验失败后要求大模型修复，只使用正文
+)
+
+// DefaultRuleTemperature front matter 未设置温度时使用的采样温度
+const DefaultRuleTemperature = 0.1
+
+// frontMatterDelimiter front matter 的起止行
+const frontMatterDelimiter = "---"
+
+// Template 规则模板，由可选的 yaml front matter 和模板正文组成
+//
+//	---
+//	system: 你是一个专业的代码补全助手
+//	temperature: 0.2
+//	---
+//	请按照需求补全选中的代码
+type Template struct {
+	Name string // 模板名称，即文件名
+	Path string // 覆盖内置模板的文件路径，使用内置模板时为空
+	Body string // 模板正文
+	TemplateMeta
+}
+
+// TemplateMeta 模板 front matter 中声明的元数据，未设置的字段使用默认值
+type TemplateMeta struct {
+	System         string   `yaml:"system"`         // 系统提示词
+	Temperature    *float32 `yaml:"temperature"`    // 采样温度，命令行参数和配置文件中的温度优先
+	Model          string   `yaml:"model"`          // 模型名称，命令行参数和配置文件中的模型优先
+	MaxTokens      int      `yaml:"maxTokens"`      // 最大输出 token 数，0 表示使用服务端默认值
+	ResponseFormat string   `yaml:"responseFormat"` // 大模型返回修改的格式，只用于代码修改模板
+}
+
+// CodeRuleTemplates 各返回格式对应的代码修改模板
+var CodeRuleTemplates = map[string]string{
+	FormatReplace:       CodeRule,
+	FormatSearchReplace: CodeSearchReplaceRule,
+	FormatDiff:          CodeDiffRule,
+}
+
+// TemplateNames 内置的规则模板名称
+func TemplateNames() []string {
+	entries, _ := embedded.ReadDir(".")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// AllTemplateNames 内置模板和规则目录中的模板名称，按名称排序
+func AllTemplateNames() []string {
+	seen := make(map[string]bool)
+	names := TemplateNames()
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, dir := range Dirs() {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if name := entry.Name(); !entry.IsDir() && filepath.Ext(name) == ".tmpl" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTemplate 加载并解析规则模板，language 不为空时优先使用该语言的模板，如 code_rule.go.tmpl
+func LoadTemplate(name, language string) (*Template, error) {
+	if variant := LanguageTemplateName(name, language); variant != "" {
+		content, path, found, err := findTemplate(variant)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return parseTemplate(variant, content, path)
+		}
+	}
+	content, path, err := ReadTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplate(name, content, path)
+}
+
+// languagePattern 可以作为模板文件名后缀的语言名称
+var languagePattern = regexp.MustCompile(`^[a-z0-9_+-]+$`)
+
+// LanguageTemplateName 语言专用的模板名称，如 code_rule.tmpl 对应 code_rule.go.tmpl，语言为空时返回空字符串
+func LanguageTemplateName(name, language string) string {
+	if !languagePattern.MatchString(language) {
+		return ""
+	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + language + ext
+}
+
+func parseTemplate(name, content, path string) (*Template, error) {
+	t, err := ParseTemplate(name, content)
+	if err != nil {
+		if path == "" {
+			return nil, fmt.Errorf("内置规则模板 %s: %w", name, err)
+		}
+		return nil, fmt.Errorf("规则模板 %s: %w", path, err)
+	}
+	t.Path = path
+	return t, nil
+}
+
+// ReadTemplate 读取规则模板的原始内容，依次查找项目规则目录、用户规则目录中的同名文件，找不到时使用内置模板
+// path 为覆盖内置模板的文件路径，使用内置模板时为空
+func ReadTemplate(name string) (content, path string, err error) {
+	content, path, found, err := findTemplate(name)
+	if err != nil {
+		return "", "", err
+	}
+	if !found {
+		return "", "", fmt.Errorf("规则模板 %s 不存在，可选值: %s", name, strings.Join(TemplateNames(), ", "))
+	}
+	return content, path, nil
+}
+
+// findTemplate 依次在项目规则目录、用户规则目录和内置模板中查找模板
+func findTemplate(name string) (content, path string, found bool, err error) {
+	dirs := Dirs()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		path := filepath.Join(dirs[i], name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", "", false, fmt.Errorf("读取规则模板失败: %w", err)
+		}
+		return string(data), path, true, nil
+	}
+	data, err := embedded.ReadFile(name)
+	if err != nil {
+		return "", "", false, nil
+	}
+	return string(data), "", true, nil
+}
+
+// BuiltinTemplate 读取内置规则模板的原始内容
+func BuiltinTemplate(name string) (string, error) {
+	data, err := embedded.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("规则模板 %s 不存在，可选值: %s", name, strings.Join(TemplateNames(), ", "))
+	}
+	return string(data), nil
+}
+
+// ParseTemplate 解析模板内容，内容以 --- 开头时将其后到下一个 --- 行之间的内容作为 yaml front matter
+func ParseTemplate(name, content string) (*Template, error) {
+	t := &Template{Name: name, Body: content}
+	meta, body, ok := splitFrontMatter(content)
+	if !ok {
+		return t, nil
+	}
+	dec := yaml.NewDecoder(strings.NewReader(meta))
+	dec.KnownFields(true)
+	if err := dec.Decode(&t.TemplateMeta); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("解析 front matter 失败: %w", err)
+	}
+	if err := t.TemplateMeta.validate(); err != nil {
+		return nil, err
+	}
+	t.Body = body
+	return t, nil
+}
+
+// splitFrontMatter 拆分 front matter 和正文，没有 front matter 时 ok 为 false
+func splitFrontMatter(content string) (meta, body string, ok bool) {
+	firstLine, rest, found := strings.Cut(content, "\n")
+	if !found || strings.TrimRight(firstLine, "\r") != frontMatterDelimiter {
+		return "", content, false
+	}
+	var b strings.Builder
+	for rest != "" {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		if strings.TrimRight(line, "\r") == frontMatterDelimiter {
+			return b.String(), rest, true
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return "", content, false
+}
+
+func (m *TemplateMeta) validate() error {
+	if m.Temperature != nil && (*m.Temperature < 0 || *m.Temperature > 2) {
+		return fmt.Errorf("temperature 必须在 0 到 2 之间")
+	}
+	if m.MaxTokens < 0 {
+		return fmt.Errorf("maxTokens 不能为负数")
+	}
+	if m.ResponseFormat != "" {
+		return CheckResponseFormat(m.ResponseFormat)
+	}
+	return nil
+}
+
+// Merge 用 other 中已设置的字段覆盖元数据
+func (m TemplateMeta) Merge(other TemplateMeta) TemplateMeta {
+	if other.System != "" {
+		m.System = other.System
+	}
+	if other.Temperature != nil {
+		m.Temperature = other.Temperature
+	}
+	if other.Model != "" {
+		m.Model = other.Model
+	}
+	if other.MaxTokens > 0 {
+		m.MaxTokens = other.MaxTokens
+	}
+	if other.ResponseFormat != "" {
+		m.ResponseFormat = other.ResponseFormat
+	}
+	return m
+}
+
+// Source 模板来源，用于提示信息
+func (t *Template) Source() string {
+	if t.Path == "" {
+		return "内置"
+	}
+	return t.Path
+}
+
+// Messages 模板对应的对话消息：front matter 中的系统提示词和用户消息
+func (t *Template) Messages(prompt string) []openai.Message {
+	return append(t.SystemMessages(), openai.Message{Role: openai.RoleUser, Content: prompt})
+}
+
+// SystemMessages 系统提示词消息，未设置系统提示词时为空
+func (t *Template) SystemMessages() []openai.Message {
+	if t.System == "" {
+		return nil
+	}
+	return []openai.Message{{Role: openai.RoleSystem, Content: t.System}}
+}
+
+// Request 按 front matter 中的温度和最大输出 token 数创建对话请求
+func (t *Template) Request(messages []openai.Message) openai.ChatRequest {
+	return openai.ChatRequest{
+		Messages:    messages,
+		Temperature: t.TemperatureOrDefault(),
+		MaxTokens:   t.MaxTokens,
+	}
+}
+
+// TemperatureOrDefault front matter 中的温度，未设置时为 DefaultRuleTemperature
+func (t *Template) TemperatureOrDefault() float32 {
+	if t.Temperature != nil {
+		return *t.Temperature
+	}
+	return DefaultRuleTemperature
+}
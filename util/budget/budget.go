@@ -0,0 +1,371 @@
+package budget
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MenciusCheng/go-cli/util/lang"
+)
+
+// surroundLines 选中区域前后始终保留的行数
+const surroundLines = 10
+
+// markerReserve 为省略标记预留的 token 数
+const markerReserve = 64
+
+// Request 裁剪文件内容的参数
+type Request struct {
+	Text      string    // 完整文件内容
+	Language  string    // 文件语言，Go 文件按语法树选择导入和所在的声明
+	StartLine int       // 选中区域开始行号，从 1 开始，为 0 时没有选中区域
+	EndLine   int       // 选中区域结束行号
+	MaxTokens int       // 最大 token 数
+	MaxRunes  int       // 最大字符数，为 0 时不限制
+	Tokenizer Tokenizer // 为空时使用 DefaultTokenizer
+}
+
+// LineRange 行号范围，从 1 开始，包含首尾
+type LineRange struct {
+	Start int
+	End   int
+}
+
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return fmt.Sprintf("%d", r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// Result 裁剪结果
+type Result struct {
+	Text        string      // 裁剪后的内容，省略的行替换为一行省略标记
+	Tokens      int         // 完整文件内容的 token 数
+	Lines       int         // 完整文件内容的行数
+	Elided      []LineRange // 省略的行
+	ElidedLines int         // 省略的行数
+	OverBudget  bool        // 选中区域和前后保留的行已经超出预算
+}
+
+// Summary 省略情况的说明，没有省略时为空
+func (r Result) Summary() string {
+	if len(r.Elided) == 0 {
+		return ""
+	}
+	ranges := make([]string, 0, len(r.Elided))
+	for _, e := range r.Elided {
+		ranges = append(ranges, e.String())
+	}
+	s := fmt.Sprintf("文件共 %d 行（约 %d tokens），超出上下文预算，省略了 %d 行：第 %s 行",
+		r.Lines, r.Tokens, r.ElidedLines, strings.Join(ranges, "、"))
+	if r.OverBudget {
+		s += "；选中的代码本身已超出预算"
+	}
+	return s
+}
+
+// Fit 按预算裁剪文件内容，内容未超出预算时原样返回，否则依次保留：
+//  1. 选中区域及前后 surroundLines 行，超出预算时也会保留
+//  2. 导入声明
+//  3. 选中区域所在的声明，完整的声明放不下时只保留声明的开头
+//  4. 其余内容，从离选中区域最近的行开始
+func Fit(req Request) Result {
+	tok := req.Tokenizer
+	if tok == nil {
+		tok = DefaultTokenizer
+	}
+	lines := strings.SplitAfter(req.Text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	tokens := make([]int, len(lines))
+	runes := make([]int, len(lines))
+	totalTokens, totalRunes := 0, 0
+	for i, line := range lines {
+		tokens[i] = tok.Count(line)
+		runes[i] = utf8.RuneCountInString(line)
+		totalTokens += tokens[i]
+		totalRunes += runes[i]
+	}
+	result := Result{Text: req.Text, Tokens: totalTokens, Lines: len(lines)}
+	if totalTokens <= req.MaxTokens && (req.MaxRunes <= 0 || totalRunes <= req.MaxRunes) {
+		return result
+	}
+
+	p := &packer{
+		keep:      make([]bool, len(lines)),
+		tokens:    tokens,
+		runes:     runes,
+		maxTokens: req.MaxTokens - markerReserve,
+		maxRunes:  req.MaxRunes,
+	}
+	n := len(lines)
+	start, end := req.StartLine-1, req.EndLine-1
+	hasSelection := req.StartLine > 0 && start < n
+	if hasSelection {
+		end = max(start, min(end, n-1))
+		p.force(max(0, start-surroundLines), min(n-1, end+surroundLines))
+		result.OverBudget = p.usedTokens > p.maxTokens || p.maxRunes > 0 && p.usedRunes > p.maxRunes
+	} else {
+		start, end = 0, -1
+	}
+
+	var outline outline
+	if lang.Normalize(req.Language) == lang.Go {
+		outline = goOutline(req.Text, start+1, end+1)
+	} else {
+		outline = textOutline(lines, start, hasSelection)
+	}
+	for _, r := range outline.imports {
+		p.tryAdd(r.Start-1, r.End-1)
+	}
+	for _, d := range outline.enclosing {
+		if !p.tryAdd(d.full.Start-1, d.full.End-1) {
+			p.tryAdd(d.header.Start-1, d.header.End-1)
+		}
+	}
+
+	// 从选中区域向两侧逐行扩展，一侧放不下时停止该侧
+	up, down := true, true
+	for above, below := start-1, end+1; up || down; above, below = above-1, below+1 {
+		up = up && above >= 0 && p.tryAdd(above, above)
+		down = down && below < n && p.tryAdd(below, below)
+	}
+
+	var b strings.Builder
+	for i := 0; i < n; {
+		if p.keep[i] {
+			b.WriteString(lines[i])
+			i++
+			continue
+		}
+		j := i
+		for j < n && !p.keep[j] {
+			j++
+		}
+		r := LineRange{Start: i + 1, End: j}
+		marker := fmt.Sprintf("… 省略第 %s 行 …\n", r)
+		// 只省略一行且不比省略标记长时保留原内容，如空行
+		if j == i+1 && tokens[i] <= tok.Count(marker) {
+			b.WriteString(lines[i])
+			i++
+			continue
+		}
+		result.Elided = append(result.Elided, r)
+		result.ElidedLines += j - i
+		b.WriteString(marker)
+		i = j
+	}
+	result.Text = b.String()
+	return result
+}
+
+// packer 记录已保留的行和用量
+type packer struct {
+	keep                []bool
+	tokens, runes       []int
+	usedTokens          int
+	usedRunes           int
+	maxTokens, maxRunes int
+}
+
+// force 保留 [from, to] 行，不检查预算
+func (p *packer) force(from, to int) {
+	for i := from; i <= to; i++ {
+		if !p.keep[i] {
+			p.keep[i] = true
+			p.usedTokens += p.tokens[i]
+			p.usedRunes += p.runes[i]
+		}
+	}
+}
+
+// tryAdd 预算足够时保留 [from, to] 行，返回是否保留
+func (p *packer) tryAdd(from, to int) bool {
+	if from < 0 || to >= len(p.keep) || from > to {
+		return false
+	}
+	addTokens, addRunes := 0, 0
+	for i := from; i <= to; i++ {
+		if !p.keep[i] {
+			addTokens += p.tokens[i]
+			addRunes += p.runes[i]
+		}
+	}
+	if p.usedTokens+addTokens > p.maxTokens || p.maxRunes > 0 && p.usedRunes+addRunes > p.maxRunes {
+		return false
+	}
+	p.force(from, to)
+	return true
+}
+
+// outline 需要优先保留的结构
+type outline struct {
+	imports   []LineRange
+	enclosing []declRange // 从外到内
+}
+
+// declRange 选中区域所在的声明，header 为声明的开头，如函数签名
+type declRange struct {
+	full   LineRange
+	header LineRange
+}
+
+// goOutline 根据 Go 语法树获取包声明、导入和选中区域所在的声明，语法错误时使用能解析的部分
+func goOutline(text string, startLine, endLine int) outline {
+	var o outline
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, "", text, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil {
+		return o
+	}
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+	pkgStart := file.Package
+	if file.Doc != nil {
+		pkgStart = file.Doc.Pos()
+	}
+	o.imports = append(o.imports, LineRange{line(pkgStart), line(file.Name.End())})
+	for _, decl := range file.Decls {
+		from, to := line(decl.Pos()), line(decl.End())
+		d, isGen := decl.(*ast.GenDecl)
+		if isGen && d.Tok == token.IMPORT {
+			o.imports = append(o.imports, LineRange{from, to})
+			continue
+		}
+		if endLine < from || startLine > to || startLine == 0 {
+			continue
+		}
+		headerEnd := from
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				from = line(d.Doc.Pos())
+			}
+			if d.Body != nil {
+				headerEnd = line(d.Body.Lbrace)
+			} else {
+				headerEnd = to
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				from = line(d.Doc.Pos())
+			}
+			headerEnd = line(d.Pos())
+		}
+		o.enclosing = append(o.enclosing, declRange{full: LineRange{from, to}, header: LineRange{from, headerEnd}})
+	}
+	return o
+}
+
+// importPattern 常见语言的导入语句
+var importPattern = regexp.MustCompile(`^\s*(import\b|from\s+\S+\s+import\b|#include\b|using\s+[\w.]+\s*;|require\b|use\s+\w|package\b)`)
+
+// importScanLines 在文件开头查找导入语句的行数
+const importScanLines = 200
+
+// textOutline 按行获取导入语句，按缩进获取选中区域所在的代码块开头，如 class、def 所在的行
+func textOutline(lines []string, start int, hasSelection bool) outline {
+	var o outline
+	for i := 0; i < min(len(lines), importScanLines); i++ {
+		if importPattern.MatchString(lines[i]) {
+			o.imports = append(o.imports, LineRange{i + 1, i + 1})
+		}
+	}
+	if !hasSelection {
+		return o
+	}
+	current := indent(lines[start])
+	for i := start - 1; i >= 0 && current > 0; i-- {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if level := indent(lines[i]); level < current {
+			current = level
+			r := LineRange{i + 1, i + 1}
+			o.enclosing = append([]declRange{{full: r, header: r}}, o.enclosing...)
+		}
+	}
+	return o
+}
+
+// indent 行首缩进的宽度，制表符按 4 计算
+func indent(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// CaretResult 按预算截取的光标前后内容
+type CaretResult struct {
+	Prefix       string // 光标前的内容
+	Suffix       string // 光标后的内容
+	ElidedBefore int    // 光标前省略的行数
+	ElidedAfter  int    // 光标后省略的行数
+}
+
+// Summary 省略情况的说明，没有省略时为空
+func (r CaretResult) Summary() string {
+	if r.ElidedBefore == 0 && r.ElidedAfter == 0 {
+		return ""
+	}
+	return fmt.Sprintf("文件超出上下文预算，省略了光标前 %d 行、光标后 %d 行", r.ElidedBefore, r.ElidedAfter)
+}
+
+// Caret 按预算截取光标前后的内容，从光标向两侧按整行保留，光标所在行始终保留
+// 前文先使用预算的 3/4，后文使用剩余的预算，后文用不完的预算再留给前文
+func Caret(prefix, suffix string, maxTokens, maxRunes int, tok Tokenizer) CaretResult {
+	if tok == nil {
+		tok = DefaultTokenizer
+	}
+	before := strings.SplitAfter(prefix, "\n") // 最后一项为光标所在行光标前的部分
+	after := strings.SplitAfter(suffix, "\n")  // 第一项为光标所在行光标后的部分
+	usedTokens := tok.Count(before[len(before)-1]) + tok.Count(after[0])
+	usedRunes := utf8.RuneCountInString(before[len(before)-1]) + utf8.RuneCountInString(after[0])
+	fits := func(line string, maxTokens, maxRunes int) bool {
+		tokens, runes := tok.Count(line), utf8.RuneCountInString(line)
+		if usedTokens+tokens > maxTokens || maxRunes > 0 && usedRunes+runes > maxRunes {
+			return false
+		}
+		usedTokens += tokens
+		usedRunes += runes
+		return true
+	}
+
+	start, end := len(before)-1, 1
+	takeBefore := func(maxTokens, maxRunes int) {
+		for start > 0 && fits(before[start-1], maxTokens, maxRunes) {
+			start--
+		}
+	}
+	takeBefore(maxTokens*3/4, maxRunes*3/4)
+	for end < len(after) && fits(after[end], maxTokens, maxRunes) {
+		end++
+	}
+	takeBefore(maxTokens, maxRunes)
+
+	r := CaretResult{
+		Prefix:       strings.Join(before[start:], ""),
+		Suffix:       strings.Join(after[:end], ""),
+		ElidedBefore: start,
+		ElidedAfter:  len(after) - end,
+	}
+	// 文件以换行结尾时最后一项为空字符串，不算作省略的行
+	if r.ElidedAfter > 0 && after[len(after)-1] == "" {
+		r.ElidedAfter--
+	}
+	return r
+}
@@ -0,0 +1,66 @@
+package budget
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer 计算文本的 token 数
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// DefaultTokenizer 默认的 token 估算器
+var DefaultTokenizer Tokenizer = Estimator{}
+
+// Estimator 近似 BPE 分词的 token 估算器，无需下载词表，结果是估算值而不是实际的分词结果
+//   - 连续的英文字母、数字和下划线按每 3 个字符 1 个 token 计算，代码中的标识符常被拆成多个 token
+//   - 连续的中文、日文和韩文按每个字 1.5 个 token 计算，生僻字在部分模型的词表中会拆成多个字节 token
+//   - 其他非 ASCII 字符按 UTF-8 编码每 2 个字节 1 个 token 计算，如 emoji
+//   - 标点符号和换行各算 1 个 token
+//   - 连续的空格和制表符按每 4 个字符 1 个 token 计算，单个空格并入后面的单词
+//
+// 各提供方的词表不同，中文通常每个字 0.6 到 1.5 个 token，估算值按较高的一端计算，用于预算时更安全
+type Estimator struct{}
+
+func (Estimator) Count(text string) int {
+	n, word, space, cjk, other := 0, 0, 0, 0, 0
+	flush := func() {
+		n += (word+2)/3 + space/4 + (cjk*3+1)/2 + (other+1)/2
+		word, space, cjk, other = 0, 0, 0, 0
+	}
+	for _, r := range text {
+		switch {
+		case r < unicode.MaxASCII && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if space > 0 || cjk > 0 || other > 0 {
+				flush()
+			}
+			word++
+		case r == ' ' || r == '\t':
+			if word > 0 || cjk > 0 || other > 0 {
+				flush()
+			}
+			space++
+		case isCJK(r):
+			if word > 0 || other > 0 {
+				flush()
+			}
+			cjk++
+		case r > unicode.MaxASCII:
+			if word > 0 || cjk > 0 {
+				flush()
+			}
+			other += utf8.RuneLen(r)
+		default:
+			flush()
+			n++
+		}
+	}
+	flush()
+	return n
+}
+
+// isCJK 是否为中文、日文或韩文字符
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
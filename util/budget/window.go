@@ -0,0 +1,19 @@
+package budget
+
+// DefaultContextWindow 模型未声明上下文窗口时使用的大小（token 数）
+const DefaultContextWindow = 8192
+
+// DefaultOutputReserve 未声明 maxTokens 时为回答预留的 token 数，不超过上下文窗口的四分之一
+const DefaultOutputReserve = 4096
+
+// InputTokens 上下文窗口中可以用于输入的 token 数：窗口减去为回答预留的 reserve 个 token，并留出 5% 的余量
+// window 为 0 时使用 DefaultContextWindow，reserve 为 0 时使用 DefaultOutputReserve
+func InputTokens(window, reserve int) int {
+	if window <= 0 {
+		window = DefaultContextWindow
+	}
+	if reserve <= 0 {
+		reserve = min(DefaultOutputReserve, window/4)
+	}
+	return window - window/20 - reserve
+}
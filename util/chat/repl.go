@@ -0,0 +1,358 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/budget"
+	"github.com/MenciusCheng/go-cli/util/lang"
+	"github.com/MenciusCheng/go-cli/util/markdown"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/util/session"
+)
+
+// REPL 终端交互式对话
+type REPL struct {
+	In             io.Reader
+	Out            io.Writer
+	Model          openai.ChatModel
+	Template       *rule.Template                                         // 系统提示词和请求参数
+	Provider       string                                                 // 当前提供方，记录到会话中
+	BaseURL        string                                                 // 当前的自定义服务地址，记录到会话中
+	NewModel       func(provider, model string) (openai.ChatModel, error) // 切换模型，provider 为空时使用当前提供方
+	Session        *session.Session
+	Store          *session.Store // 会话存储，为空时不保存会话
+	FilePath       string         // 最近附加的文件路径，记录到会话中
+	MaxContextSize int            // 每个附加文件的最大长度（字符数），为 0 时只按模型的上下文窗口裁剪
+	Markdown       bool           // 在终端中渲染 markdown 格式的回答
+
+	attachments []attachment // 待随下一条消息发送的文件内容
+}
+
+// attachment 附加的文件内容，发送时按上下文窗口裁剪
+type attachment struct {
+	label    string
+	language string
+	text     string
+}
+
+// NewREPL 创建交互式对话，store 不为空时每轮问答都会保存到新的会话中
+func NewREPL(in io.Reader, out io.Writer, model openai.ChatModel, tmpl *rule.Template, store *session.Store) *REPL {
+	r := &REPL{
+		In:       in,
+		Out:      out,
+		Model:    model,
+		Template: tmpl,
+		Store:    store,
+	}
+	r.newSession()
+	return r
+}
+
+// newSession 开始新的会话
+func (r *REPL) newSession() {
+	r.Session = &session.Session{}
+	if r.Store != nil {
+		r.Session.ID = r.Store.NewID()
+	}
+}
+
+const helpText = `可用命令：
+  /file <路径>[:开始行[-结束行]]  附加文件或部分行，随下一条消息发送
+  /clear                          清空对话历史和待发送的文件，开始新的会话
+  /model [提供方] [模型]          查看或切换模型
+  /save [路径]                    将对话保存为 markdown 文件
+  /help                           查看帮助
+  /exit                           退出对话（也可以使用 Ctrl-D）
+行尾输入 \ 可以继续输入下一行，回答过程中按 Ctrl-C 中断回答`
+
+// Run 运行对话循环，first 不为空时作为第一条消息发送
+func (r *REPL) Run(first string) error {
+	fmt.Fprintf(r.Out, "进入对话模式，模型: %s，输入 /help 查看命令，/exit 或 Ctrl-D 退出\n", r.Model.ModelName())
+	if r.Store != nil {
+		if n := len(r.Session.Turns); n > 0 {
+			fmt.Fprintf(r.Out, "已恢复会话 %s（%d 轮问答）\n", r.Session.ID, n)
+		} else {
+			fmt.Fprintf(r.Out, "会话编号: %s\n", r.Session.ID)
+		}
+	}
+	if first != "" {
+		fmt.Fprintf(r.Out, "\n> %s\n", first)
+		r.send(first)
+	}
+
+	scanner := bufio.NewScanner(r.In)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for {
+		input, ok := r.readInput(scanner)
+		if !ok {
+			fmt.Fprintln(r.Out)
+			return scanner.Err()
+		}
+		input = strings.TrimSpace(input)
+		switch {
+		case input == "":
+			continue
+		case strings.HasPrefix(input, "/"):
+			exit, err := r.command(input)
+			if err != nil {
+				fmt.Fprintf(r.Out, "错误: %v\n", err)
+			}
+			if exit {
+				return nil
+			}
+		default:
+			r.send(input)
+		}
+	}
+}
+
+// readInput 读取一条输入，行尾为 \ 时继续读取下一行
+func (r *REPL) readInput(scanner *bufio.Scanner) (string, bool) {
+	var lines []string
+	prompt := "\n> "
+	for {
+		fmt.Fprint(r.Out, prompt)
+		if !scanner.Scan() {
+			return strings.Join(lines, "\n"), len(lines) > 0
+		}
+		line := scanner.Text()
+		if !strings.HasSuffix(line, `\`) {
+			return strings.Join(append(lines, line), "\n"), true
+		}
+		lines = append(lines, strings.TrimSuffix(line, `\`))
+		prompt = "... "
+	}
+}
+
+// send 发送一条消息并流式输出回答，回答失败时不记录到会话中
+func (r *REPL) send(input string) {
+	content, maxTokens := r.content(input)
+	messages, dropped := r.Session.MessagesWithin(r.Template.System, maxTokens-budget.DefaultTokenizer.Count(content))
+	if dropped > 0 {
+		fmt.Fprintf(r.Out, "对话历史超出上下文窗口，本次省略了最早的 %d 轮问答\n", dropped)
+	}
+	messages = append(messages, openai.Message{Role: openai.RoleUser, Content: content})
+
+	// 回答过程中按 Ctrl-C 只中断本次回答
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintln(r.Out)
+	var answer strings.Builder
+	out := r.Out
+	var renderer *markdown.Renderer
+	if r.Markdown {
+		renderer = markdown.NewRenderer(r.Out)
+		out = renderer
+	}
+	resp, err := r.Model.Stream(ctx, r.Template.Request(messages), func(token string) {
+		answer.WriteString(token)
+		fmt.Fprint(out, token)
+	})
+	if renderer != nil {
+		renderer.Flush()
+	}
+	fmt.Fprintln(r.Out)
+	if err != nil && (ctx.Err() == nil || answer.Len() == 0) {
+		if ctx.Err() != nil {
+			fmt.Fprintln(r.Out, "（已中断）")
+		} else {
+			fmt.Fprintf(r.Out, "错误: 咨询大模型失败: %v\n", err)
+		}
+		return
+	}
+	if err != nil {
+		// 中断时保留已输出的部分回答
+		fmt.Fprintln(r.Out, "（已中断）")
+	}
+	r.attachments = nil
+
+	turn := session.Turn{
+		Time:     time.Now(),
+		Prompt:   input,
+		FilePath: r.FilePath,
+		Answer:   answer.String(),
+		Provider: r.Provider,
+		BaseURL:  r.BaseURL,
+		Model:    r.Model.ModelName(),
+	}
+	if content != input {
+		turn.Rendered = content
+	}
+	if resp != nil {
+		turn.Usage = resp.Usage
+	}
+	r.Session.Turns = append(r.Session.Turns, turn)
+	if r.Store != nil {
+		if err := r.Store.Append(r.Session.ID, turn); err != nil {
+			fmt.Fprintf(r.Out, "警告: 保存会话失败: %v\n", err)
+		}
+	}
+}
+
+// command 执行斜杠命令，返回是否退出对话
+func (r *REPL) command(input string) (bool, error) {
+	fields := strings.Fields(input)
+	args := fields[1:]
+	switch fields[0] {
+	case "/exit", "/quit":
+		return true, nil
+	case "/help":
+		fmt.Fprintln(r.Out, helpText)
+	case "/clear":
+		r.newSession()
+		r.attachments = nil
+		fmt.Fprintln(r.Out, "已清空对话历史，开始新的会话")
+	case "/file":
+		if len(args) != 1 {
+			return false, fmt.Errorf("用法: /file <路径>[:开始行[-结束行]]")
+		}
+		return false, r.AttachFile(args[0])
+	case "/model":
+		return false, r.switchModel(args)
+	case "/save":
+		return false, r.save(args)
+	default:
+		return false, fmt.Errorf("未知命令 %s，输入 /help 查看可用命令", fields[0])
+	}
+	return false, nil
+}
+
+// AttachFile 附加文件内容，spec 格式为 路径[:开始行[-结束行]]
+func (r *REPL) AttachFile(spec string) error {
+	path, start, end, err := parseFileSpec(spec)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	label := fmt.Sprintf("文件 `%s`", path)
+	if start > 0 {
+		if end == 0 || end > len(lines) {
+			end = len(lines)
+		}
+		if start > end {
+			return fmt.Errorf("行号超出范围: 文件共 %d 行", len(lines))
+		}
+		lines = lines[start-1 : end]
+		label += fmt.Sprintf(" 第 %d-%d 行", start, end)
+	}
+	r.FilePath = path
+	r.AttachText(label, strings.Join(lines, "\n"))
+	fmt.Fprintf(r.Out, "已附加%s（%d 行），将随下一条消息发送\n", label, len(lines))
+	return nil
+}
+
+// AttachText 附加一段代码，随下一条消息发送，按 FilePath 识别代码的语言
+func (r *REPL) AttachText(label, text string) {
+	r.attachments = append(r.attachments, attachment{label: label, language: lang.FromPath(r.FilePath), text: text})
+}
+
+// content 将待发送的文件附加到问题之前，返回发送的内容和上下文窗口中可以用于输入的 token 数
+// 每个文件平分问题之外的预算，超出时按 budget.Fit 裁剪，对话历史使用剩余的预算
+func (r *REPL) content(input string) (string, int) {
+	tok := budget.DefaultTokenizer
+	maxTokens := budget.InputTokens(r.Model.Capabilities().ContextWindow, r.Template.MaxTokens) - tok.Count(r.Template.System)
+	if len(r.attachments) == 0 {
+		return input, maxTokens
+	}
+	question := "问题如下：\n" + input
+	share := (maxTokens - tok.Count(question)) / len(r.attachments)
+	parts := make([]string, 0, len(r.attachments)+1)
+	for _, a := range r.attachments {
+		result := budget.Fit(budget.Request{
+			Text:      a.text,
+			Language:  a.language,
+			MaxTokens: share,
+			MaxRunes:  r.MaxContextSize,
+		})
+		if summary := result.Summary(); summary != "" {
+			fmt.Fprintf(r.Out, "%s：%s\n", a.label, summary)
+		}
+		parts = append(parts, fmt.Sprintf("%s内容如下：\n```\n%s\n```", a.label, strings.TrimSuffix(result.Text, "\n")))
+	}
+	return strings.Join(append(parts, question), "\n\n"), maxTokens
+}
+
+// parseFileSpec 解析 路径[:开始行[-结束行]]
+func parseFileSpec(spec string) (string, int, int, error) {
+	i := strings.LastIndex(spec, ":")
+	if i < 0 {
+		return spec, 0, 0, nil
+	}
+	path, lineRange := spec[:i], spec[i+1:]
+	startStr, endStr, hasEnd := strings.Cut(lineRange, "-")
+	start, err := strconv.Atoi(startStr)
+	if err != nil || start <= 0 {
+		// 不是行号，整体作为路径
+		return spec, 0, 0, nil
+	}
+	end := start
+	if hasEnd {
+		if end, err = strconv.Atoi(endStr); err != nil || end < start {
+			return "", 0, 0, fmt.Errorf("无效的行号范围: %s", lineRange)
+		}
+	}
+	return path, start, end, nil
+}
+
+// switchModel 查看或切换模型，参数为 [模型]、[提供方] 或 [提供方 模型]
+func (r *REPL) switchModel(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(r.Out, "当前模型: %s\n", r.Model.ModelName())
+		return nil
+	}
+	if r.NewModel == nil {
+		return errors.New("不支持切换模型")
+	}
+	var provider, model string
+	switch len(args) {
+	case 1:
+		// 参数为提供方名称时切换到该提供方的默认模型
+		if _, ok := openai.GetProvider(args[0]); ok {
+			provider = args[0]
+		} else {
+			model = args[0]
+		}
+	case 2:
+		provider, model = args[0], args[1]
+	default:
+		return fmt.Errorf("用法: /model [提供方] [模型]")
+	}
+	m, err := r.NewModel(provider, model)
+	if err != nil {
+		return err
+	}
+	r.Model = m
+	fmt.Fprintf(r.Out, "已切换到模型: %s\n", m.ModelName())
+	return nil
+}
+
+// save 将对话保存为 markdown 文件
+func (r *REPL) save(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("用法: /save [路径]")
+	}
+	path := fmt.Sprintf("go-cli-chat-%s.md", time.Now().Format("20060102-150405"))
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if err := os.WriteFile(path, []byte(r.Session.Markdown()), 0644); err != nil {
+		return fmt.Errorf("保存对话失败: %w", err)
+	}
+	fmt.Fprintf(r.Out, "已保存 %d 轮对话到 %s\n", len(r.Session.Turns), path)
+	return nil
+}
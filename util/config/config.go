@@ -0,0 +1,447 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName 项目级配置文件名
+const ProjectFileName = ".go-cli.yaml"
+
+// Settings 可配置项
+type Settings struct {
+	Provider       string   `yaml:"provider,omitempty"`       // 大模型提供方
+	Model          string   `yaml:"model,omitempty"`          // 模型名称
+	BaseURL        string   `yaml:"baseURL,omitempty"`        // 自定义服务地址
+	Temperature    *float32 `yaml:"temperature,omitempty"`    // 采样温度
+	MaxContextSize *int     `yaml:"maxContextSize,omitempty"` // 最大上下文长度（字符数），0 表示不限制，可覆盖低优先级配置中的值
+	ResponseFormat string   `yaml:"responseFormat,omitempty"` // 代码补全的返回格式
+}
+
+// Config 配置文件内容
+type Config struct {
+	Settings `yaml:",inline"`
+	Profile  string               `yaml:"profile,omitempty"`  // 默认使用的 profile
+	Profiles map[string]*Settings `yaml:"profiles,omitempty"` // 命名 profile
+	Hooks    []Hook               `yaml:"hooks,omitempty"`    // 在策略执行前后运行的外部命令
+}
+
+// hook 运行时机
+const (
+	HookBefore = "before" // 选择策略前，失败时中止执行
+	HookAfter  = "after"  // 策略执行后，无论成功或失败
+	HookEdit   = "edit"   // 策略写入文件后，如执行 goimports
+)
+
+// HookWhens 支持的 hook 运行时机
+var HookWhens = []string{HookBefore, HookAfter, HookEdit}
+
+// Hook 在策略执行前后运行的外部命令，通过 sh -c 执行
+type Hook struct {
+	When    string `yaml:"when"`              // 运行时机：before、after、edit
+	Run     string `yaml:"run"`               // 执行的命令
+	Files   string `yaml:"files,omitempty"`   // 只对匹配的文件运行，按文件名匹配，如 *.go
+	Timeout int    `yaml:"timeout,omitempty"` // 超时时间（秒），为 0 时默认 60 秒
+}
+
+// check 检查 hook 配置是否正确
+func (h *Hook) check() error {
+	switch h.When {
+	case HookBefore, HookAfter, HookEdit:
+	default:
+		return fmt.Errorf("不支持的 hook 运行时机 %q，可选值: %s", h.When, strings.Join(HookWhens, ", "))
+	}
+	if strings.TrimSpace(h.Run) == "" {
+		return fmt.Errorf("hook 的 run 不能为空")
+	}
+	return nil
+}
+
+// Keys 支持的配置项
+var Keys = []string{"provider", "model", "baseURL", "temperature", "maxContextSize", "responseFormat"}
+
+// CheckKey 检查配置项是否支持
+func CheckKey(key string) error {
+	for _, k := range Keys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("未知的配置项 %q，可选值: %s", key, strings.Join(Keys, ", "))
+}
+
+// envKeys 配置项对应的环境变量
+var envKeys = map[string]string{
+	"provider":       "GO_CLI_PROVIDER",
+	"model":          "GO_CLI_MODEL",
+	"baseURL":        "GO_CLI_BASE_URL",
+	"temperature":    "GO_CLI_TEMPERATURE",
+	"maxContextSize": "GO_CLI_MAX_CONTEXT_SIZE",
+	"responseFormat": "GO_CLI_RESPONSE_FORMAT",
+}
+
+// ProfileEnv 指定 profile 的环境变量
+const ProfileEnv = "GO_CLI_PROFILE"
+
+// Dir 用户配置目录，默认为 ~/.config/go-cli，设置 XDG_CONFIG_HOME 时使用 $XDG_CONFIG_HOME/go-cli
+func Dir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "go-cli")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".config", "go-cli")
+	}
+	return filepath.Join(home, ".config", "go-cli")
+}
+
+// StateDir 用户状态目录，用于保存编辑记录等运行数据
+// 默认为 ~/.local/state/go-cli，设置 XDG_STATE_HOME 时使用 $XDG_STATE_HOME/go-cli
+func StateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "go-cli")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".local", "state", "go-cli")
+	}
+	return filepath.Join(home, ".local", "state", "go-cli")
+}
+
+// UserPath 用户配置文件路径
+func UserPath() string {
+	return filepath.Join(Dir(), "config.yaml")
+}
+
+// ProjectPath 从当前目录向上查找项目配置文件，找不到时返回空字符串
+func ProjectPath() string {
+	return findUp(ProjectFileName, false)
+}
+
+// UserRulesDir 用户规则目录 ~/.config/go-cli/rules
+func UserRulesDir() string {
+	return filepath.Join(Dir(), "rules")
+}
+
+// ProjectRulesDir 从当前目录向上查找项目规则目录 .go-cli/rules，找不到时返回空字符串
+func ProjectRulesDir() string {
+	return findUp(filepath.Join(".go-cli", "rules"), true)
+}
+
+// findUp 从当前目录向上查找文件或目录，找不到时返回空字符串
+func findUp(name string, isDir bool) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && info.IsDir() == isDir {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadFile 读取配置文件，文件不存在时返回空配置
+func LoadFile(path string) (*Config, error) {
+	c := &Config{}
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return c, nil
+}
+
+// SaveFile 写入配置文件，文件已存在时保留其中的注释和配置项顺序
+func SaveFile(path string, c *Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+	var node yaml.Node
+	if err := node.Encode(c); err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&node}}
+	if data, err := os.ReadFile(path); err == nil {
+		var old yaml.Node
+		if err := yaml.Unmarshal(data, &old); err == nil && len(old.Content) > 0 {
+			mergeNode(old.Content[0], &node)
+			doc = &old
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	return nil
+}
+
+// mergeNode 用 src 更新 dst：已有的配置项保留原来的位置和注释，src 中没有的配置项删除，新的配置项追加到末尾，
+// 列表按位置逐项更新
+func mergeNode(dst, src *yaml.Node) {
+	if dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode {
+		for i, item := range src.Content {
+			if i < len(dst.Content) {
+				mergeNode(dst.Content[i], item)
+			} else {
+				dst.Content = append(dst.Content, item)
+			}
+		}
+		dst.Content = dst.Content[:len(src.Content)]
+		return
+	}
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+		return
+	}
+	values := make(map[string]*yaml.Node, len(src.Content)/2)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		values[src.Content[i].Value] = src.Content[i+1]
+	}
+	content := make([]*yaml.Node, 0, len(src.Content))
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key := dst.Content[i].Value
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		mergeNode(dst.Content[i+1], value)
+		content = append(content, dst.Content[i], dst.Content[i+1])
+		delete(values, key)
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		if _, ok := values[src.Content[i].Value]; ok {
+			content = append(content, src.Content[i], src.Content[i+1])
+		}
+	}
+	dst.Content = content
+}
+
+// Resolved 合并后的配置
+type Resolved struct {
+	Settings
+	Profile      string            // 生效的 profile
+	ProfileNames []string          // 所有可用的 profile
+	Sources      map[string]string // 每个配置项的来源
+	Hooks        []Hook            // 用户配置和已信任的项目配置中的 hook，用户配置在前
+	ProjectPath  string            // 项目配置文件路径，没有项目配置时为空
+	Trusted      bool              // 项目配置是否已通过 config trust 信任
+	Ignored      []string          // 项目配置未被信任而忽略的内容，如 hooks、baseURL
+}
+
+// Resolve 按优先级合并配置：用户配置 < 项目配置 < profile < 环境变量
+// 命令行参数的优先级最高，由调用方在此基础上覆盖
+// 项目配置未通过 Trust 信任时忽略其中的 hook 和 baseURL，忽略的内容记录在 Ignored 中
+func Resolve(profile string) (*Resolved, error) {
+	user, err := LoadFile(UserPath())
+	if err != nil {
+		return nil, err
+	}
+	projectPath := ProjectPath()
+	project, err := LoadFile(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Resolved{Sources: make(map[string]string)}
+	r.ProfileNames = mergeNames(user.ProfileNames(), project.ProfileNames())
+	r.ProjectPath = projectPath
+	r.Trusted = projectPath != "" && IsTrusted(projectPath)
+	r.merge(&user.Settings, UserPath())
+	r.merge(r.project(&project.Settings), projectPath)
+	projectHooks := project.Hooks
+	if !r.Trusted && len(projectHooks) > 0 {
+		// 项目配置随仓库分发，未信任时不执行其中的命令
+		r.Ignored = append(r.Ignored, "hooks")
+		projectHooks = nil
+	}
+	for _, c := range []struct {
+		path  string
+		hooks []Hook
+	}{{UserPath(), user.Hooks}, {projectPath, projectHooks}} {
+		for i := range c.hooks {
+			if err := c.hooks[i].check(); err != nil {
+				return nil, fmt.Errorf("配置文件 %s 第 %d 个 hook: %w", c.path, i+1, err)
+			}
+		}
+		r.Hooks = append(r.Hooks, c.hooks...)
+	}
+
+	// 确定 profile：参数 > 环境变量 > 项目配置 > 用户配置
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	if profile == "" {
+		profile = project.Profile
+	}
+	if profile == "" {
+		profile = user.Profile
+	}
+	if profile != "" {
+		found := false
+		if p, ok := user.Profiles[profile]; ok && p != nil {
+			r.merge(p, fmt.Sprintf("%s (profile %s)", UserPath(), profile))
+			found = true
+		}
+		if p, ok := project.Profiles[profile]; ok && p != nil {
+			r.merge(r.project(p), fmt.Sprintf("%s (profile %s)", projectPath, profile))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("未找到 profile %q", profile)
+		}
+		r.Profile = profile
+	}
+
+	for _, key := range Keys {
+		v := os.Getenv(envKeys[key])
+		if v == "" {
+			continue
+		}
+		if err := r.Settings.Set(key, v); err != nil {
+			return nil, fmt.Errorf("环境变量 %s: %w", envKeys[key], err)
+		}
+		r.Sources[key] = "env " + envKeys[key]
+	}
+	return r, nil
+}
+
+// project 项目配置中生效的配置项，未信任时忽略 baseURL，避免把已保存的 api key 发送到仓库指定的服务地址
+func (r *Resolved) project(s *Settings) *Settings {
+	if r.Trusted || s.BaseURL == "" {
+		return s
+	}
+	if !slices.Contains(r.Ignored, "baseURL") {
+		r.Ignored = append(r.Ignored, "baseURL")
+	}
+	c := *s
+	c.BaseURL = ""
+	return &c
+}
+
+// merge 用 s 中非空的配置项覆盖当前配置
+func (r *Resolved) merge(s *Settings, source string) {
+	for _, key := range Keys {
+		if v := s.Get(key); v != "" {
+			_ = r.Settings.Set(key, v)
+			r.Sources[key] = source
+		}
+	}
+}
+
+// Get 获取配置项的字符串值，未设置时返回空字符串
+func (s *Settings) Get(key string) string {
+	switch key {
+	case "provider":
+		return s.Provider
+	case "model":
+		return s.Model
+	case "baseURL":
+		return s.BaseURL
+	case "temperature":
+		if s.Temperature == nil {
+			return ""
+		}
+		return strconv.FormatFloat(float64(*s.Temperature), 'f', -1, 32)
+	case "maxContextSize":
+		if s.MaxContextSize == nil {
+			return ""
+		}
+		return strconv.Itoa(*s.MaxContextSize)
+	case "responseFormat":
+		return s.ResponseFormat
+	}
+	return ""
+}
+
+// Set 设置配置项，value 为空字符串时清除该配置项
+func (s *Settings) Set(key, value string) error {
+	switch key {
+	case "provider":
+		s.Provider = value
+	case "model":
+		s.Model = value
+	case "baseURL":
+		s.BaseURL = value
+	case "temperature":
+		if value == "" {
+			s.Temperature = nil
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil || f < 0 || f > 2 {
+			return fmt.Errorf("temperature 必须是 0 到 2 之间的数字: %s", value)
+		}
+		t := float32(f)
+		s.Temperature = &t
+	case "maxContextSize":
+		if value == "" {
+			s.MaxContextSize = nil
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("maxContextSize 必须是非负整数: %s", value)
+		}
+		s.MaxContextSize = &n
+	case "responseFormat":
+		s.ResponseFormat = value
+	default:
+		return CheckKey(key)
+	}
+	return nil
+}
+
+// ProfileNames 获取配置中所有 profile 名称（已排序）
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mergeNames 合并去重并排序
+func mergeNames(a, b []string) []string {
+	set := make(map[string]bool)
+	for _, name := range append(a, b...) {
+		set[name] = true
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
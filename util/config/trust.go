@@ -0,0 +1,109 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TrustPath 信任的项目配置文件列表，记录每个文件的路径和内容的 sha256
+// 项目配置文件随仓库分发，其中的 hook 会执行命令，只有信任后才会生效，文件内容变化后需要重新信任
+func TrustPath() string {
+	return filepath.Join(Dir(), "trusted.yaml")
+}
+
+// Trust 信任项目配置文件的当前内容
+func Trust(path string) error {
+	abs, sum, err := fileHash(path)
+	if err != nil {
+		return err
+	}
+	trusted, err := loadTrusted()
+	if err != nil {
+		return err
+	}
+	trusted[abs] = sum
+	return saveTrusted(trusted)
+}
+
+// Untrust 取消信任项目配置文件，未信任时不做任何处理
+func Untrust(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+	trusted, err := loadTrusted()
+	if err != nil {
+		return err
+	}
+	if _, ok := trusted[abs]; !ok {
+		return nil
+	}
+	delete(trusted, abs)
+	return saveTrusted(trusted)
+}
+
+// IsTrusted 项目配置文件是否已被信任且内容没有变化
+func IsTrusted(path string) bool {
+	abs, sum, err := fileHash(path)
+	if err != nil {
+		return false
+	}
+	trusted, err := loadTrusted()
+	if err != nil {
+		return false
+	}
+	return trusted[abs] == sum
+}
+
+// fileHash 文件的绝对路径和内容的 sha256
+func fileHash(path string) (string, string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", fmt.Errorf("获取绝对路径失败: %w", err)
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return "", "", fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return abs, hex.EncodeToString(sum[:]), nil
+}
+
+// loadTrusted 读取信任列表，文件不存在时返回空列表
+func loadTrusted() (map[string]string, error) {
+	trusted := make(map[string]string)
+	data, err := os.ReadFile(TrustPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return trusted, nil
+		}
+		return nil, fmt.Errorf("读取信任列表失败: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("解析信任列表 %s 失败: %w", TrustPath(), err)
+	}
+	if trusted == nil {
+		trusted = make(map[string]string)
+	}
+	return trusted, nil
+}
+
+// saveTrusted 写入信任列表
+func saveTrusted(trusted map[string]string) error {
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+	data, err := yaml.Marshal(trusted)
+	if err != nil {
+		return fmt.Errorf("序列化信任列表失败: %w", err)
+	}
+	if err := os.WriteFile(TrustPath(), data, 0600); err != nil {
+		return fmt.Errorf("写入信任列表失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package credential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/config"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileStore 加密的本地凭证文件
+// 凭证使用 AES-256-GCM 加密后保存在 credentials.enc，密钥保存在仅当前用户可读的 credentials.key
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore 创建本地加密文件凭证存储，文件位于用户配置目录
+func NewFileStore() *FileStore {
+	return &FileStore{dir: config.Dir()}
+}
+
+func (s *FileStore) Name() string {
+	return "file"
+}
+
+func (s *FileStore) Available() bool {
+	return true
+}
+
+func (s *FileStore) dataPath() string {
+	return filepath.Join(s.dir, "credentials.enc")
+}
+
+func (s *FileStore) keyPath() string {
+	return filepath.Join(s.dir, "credentials.key")
+}
+
+func (s *FileStore) Get(provider string) (string, error) {
+	keys, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	return keys[provider], nil
+}
+
+func (s *FileStore) Set(provider, key string) error {
+	keys, err := s.load()
+	if err != nil {
+		return err
+	}
+	keys[provider] = key
+	return s.save(keys)
+}
+
+func (s *FileStore) Delete(provider string) error {
+	keys, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[provider]; !ok {
+		return nil
+	}
+	delete(keys, provider)
+	return s.save(keys)
+}
+
+// load 读取并解密凭证文件，文件不存在时返回空集合
+func (s *FileStore) load() (map[string]string, error) {
+	keys := make(map[string]string)
+	data, err := os.ReadFile(s.dataPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return keys, nil
+		}
+		return nil, fmt.Errorf("读取凭证文件失败: %w", err)
+	}
+	gcm, err := s.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("凭证文件已损坏: %s", s.dataPath())
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密凭证文件失败: %w", err)
+	}
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, fmt.Errorf("解析凭证文件失败: %w", err)
+	}
+	return keys, nil
+}
+
+// save 加密并写入凭证文件
+func (s *FileStore) save(keys map[string]string) error {
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("序列化凭证失败: %w", err)
+	}
+	gcm, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("生成随机数失败: %w", err)
+	}
+	data := gcm.Seal(nonce, nonce, plaintext, nil)
+	if err := os.WriteFile(s.dataPath(), data, 0600); err != nil {
+		return fmt.Errorf("写入凭证文件失败: %w", err)
+	}
+	return nil
+}
+
+// cipher 读取加密密钥，create 为 true 时密钥不存在则生成
+func (s *FileStore) cipher(create bool) (cipher.AEAD, error) {
+	key, err := os.ReadFile(s.keyPath())
+	if os.IsNotExist(err) && create {
+		if err := os.MkdirAll(s.dir, 0700); err != nil {
+			return nil, fmt.Errorf("创建凭证目录失败: %w", err)
+		}
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, fmt.Errorf("生成密钥失败: %w", err)
+		}
+		if err := os.WriteFile(s.keyPath(), key, 0600); err != nil {
+			return nil, fmt.Errorf("写入密钥文件失败: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("密钥文件已损坏: %s", s.keyPath())
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,67 @@
+package credential
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// keyringService 在系统钥匙环中使用的服务名
+const keyringService = "go-cli"
+
+// KeyringStore 基于 Secret Service（GNOME Keyring / KWallet）的凭证存储，通过 secret-tool 命令访问
+type KeyringStore struct{}
+
+// NewKeyringStore 创建系统钥匙环凭证存储
+func NewKeyringStore() *KeyringStore {
+	return &KeyringStore{}
+}
+
+func (s *KeyringStore) Name() string {
+	return "keyring"
+}
+
+// Available 需要安装 secret-tool 且存在 D-Bus 会话
+func (s *KeyringStore) Available() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func (s *KeyringStore) Get(provider string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", keyringService, "provider", provider).Output()
+	if err != nil {
+		// 未找到时 secret-tool 返回非零退出码且没有输出
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("读取系统钥匙环失败: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (s *KeyringStore) Set(provider, key string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", "go-cli "+provider+" api key",
+		"service", keyringService, "provider", provider)
+	cmd.Stdin = strings.NewReader(key)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("写入系统钥匙环失败: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (s *KeyringStore) Delete(provider string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", keyringService, "provider", provider)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("删除系统钥匙环凭证失败: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package credential
+
+import "encoding/json"
+
+// redacted 脱敏后的占位内容
+const redacted = "******"
+
+// Secret 敏感字符串，如 api key
+// 序列化为 JSON 或格式化输出时会被脱敏，需要原始值时使用 string(s) 转换
+type Secret string
+
+// String 实现 fmt.Stringer，避免日志中输出明文
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString 实现 fmt.GoStringer，避免 %#v 输出明文
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// MarshalJSON 序列化时脱敏
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Mask 部分脱敏，只保留首尾少量字符，用于展示 key 的来源
+// 例如: "sk-1234567890abcdef" -> "sk-****cdef"
+func Mask(key string) string {
+	runes := []rune(key)
+	if len(runes) <= 8 {
+		return redacted
+	}
+	return string(runes[:3]) + "****" + string(runes[len(runes)-4:])
+}
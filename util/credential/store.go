@@ -0,0 +1,80 @@
+package credential
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Store 凭证存储
+type Store interface {
+	// Name 存储名称
+	Name() string
+	// Available 当前环境是否可用
+	Available() bool
+	// Get 获取提供方的 api key，不存在时返回空字符串
+	Get(provider string) (string, error)
+	// Set 保存提供方的 api key
+	Set(provider, key string) error
+	// Delete 删除提供方的 api key
+	Delete(provider string) error
+}
+
+// Stores 所有凭证存储，按查找顺序排列
+func Stores() []Store {
+	return []Store{NewKeyringStore(), NewFileStore()}
+}
+
+// GetStore 根据名称获取凭证存储，名称为空时返回第一个可用的存储
+func GetStore(name string) (Store, error) {
+	for _, s := range Stores() {
+		if name == "" && s.Available() || name == s.Name() {
+			if !s.Available() {
+				return nil, fmt.Errorf("凭证存储 %s 在当前环境不可用", name)
+			}
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("未知的凭证存储 %q，可选值: keyring, file", name)
+}
+
+// Lookup 依次从可用的凭证存储中查找 api key，返回 key 和所在存储名称
+func Lookup(provider string) (string, string, error) {
+	for _, s := range Stores() {
+		if !s.Available() {
+			continue
+		}
+		key, err := s.Get(provider)
+		if err != nil {
+			return "", "", err
+		}
+		if key != "" {
+			return key, s.Name(), nil
+		}
+	}
+	return "", "", nil
+}
+
+// Providers 需要 api key 的提供方，未指定提供方时按此顺序选择已配置 api key 的提供方
+var Providers = []string{"deepseek", "qwen"}
+
+// envs 各提供方 api key 对应的环境变量，按优先级排列
+var envs = map[string][]string{
+	"deepseek": {"DEEPSEEK_API_KEY"},
+	"qwen":     {"QWEN_API_KEY", "DASHSCOPE_API_KEY"},
+}
+
+// Envs 提供方 api key 对应的环境变量，按优先级排列
+func Envs(provider string) []string {
+	return envs[strings.ToLower(provider)]
+}
+
+// LookupEnv 按优先级读取提供方 api key 的环境变量，返回变量名和值，都未设置时返回空字符串
+func LookupEnv(provider string) (string, string) {
+	for _, key := range Envs(provider) {
+		if v := os.Getenv(key); v != "" {
+			return key, v
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,280 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MenciusCheng/go-cli/util/terminal"
+)
+
+// OpKind 编辑操作类型
+type OpKind int
+
+const (
+	Equal  OpKind = iota // 相同
+	Delete               // 删除
+	Insert               // 新增
+)
+
+// Op 单行编辑操作
+type Op struct {
+	Kind OpKind
+	Text string // 行内容，不含换行符
+	A    int    // 在原文本中的行下标（Insert 时为插入位置）
+	B    int    // 在新文本中的行下标（Delete 时为插入位置）
+}
+
+// SplitLines 按换行符分割文本
+func SplitLines(text string) []string {
+	return strings.Split(text, "\n")
+}
+
+// maxEditDistance 查找最短编辑序列时编辑距离的上限，回溯记录约占 maxEditDistance² 个 int
+// 超过时不再查找最短编辑序列，将首尾相同行之间的内容整体替换
+const maxEditDistance = 1000
+
+// Lines 使用 Myers 算法计算两组行之间的最短编辑序列
+// 先去掉首尾相同的行，编辑距离超过 maxEditDistance 时将中间部分整体替换，编辑序列不一定最短
+func Lines(a, b []string) []Op {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var ops []Op
+	for i := 0; i < pre; i++ {
+		ops = append(ops, Op{Kind: Equal, Text: a[i], A: i, B: i})
+	}
+	midA, midB := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	mid, ok := myers(midA, midB)
+	if !ok {
+		mid = replaceAll(midA, midB)
+	}
+	for _, op := range mid {
+		op.A += pre
+		op.B += pre
+		ops = append(ops, op)
+	}
+	for i := 0; i < suf; i++ {
+		x, y := len(a)-suf+i, len(b)-suf+i
+		ops = append(ops, Op{Kind: Equal, Text: a[x], A: x, B: y})
+	}
+	return ops
+}
+
+// myers 查找最短编辑序列，编辑距离超过 maxEditDistance 时返回 false
+func myers(a, b []string) ([]Op, bool) {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEditDistance)
+	offset := limit
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+
+	// 前向搜索，每一步只记录 v 中 [-d, d] 的部分用于回溯
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// replaceAll 删除 a 的所有行后插入 b 的所有行
+func replaceAll(a, b []string) []Op {
+	ops := make([]Op, 0, len(a)+len(b))
+	for i, line := range a {
+		ops = append(ops, Op{Kind: Delete, Text: line, A: i, B: 0})
+	}
+	for j, line := range b {
+		ops = append(ops, Op{Kind: Insert, Text: line, A: len(a), B: j})
+	}
+	return ops
+}
+
+// backtrack 根据搜索记录回溯出编辑序列，trace[d][d+k] 为第 d 步开始时对角线 k 上的 x
+func backtrack(a, b []string, trace [][]int, d int) []Op {
+	x, y := len(a), len(b)
+	var ops []Op
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v[d+k-1] < v[d+k+1] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, Op{Kind: Equal, Text: a[x], A: x, B: y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, Op{Kind: Insert, Text: b[y], A: x, B: y})
+		} else {
+			x--
+			ops = append(ops, Op{Kind: Delete, Text: a[x], A: x, B: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, Op{Kind: Equal, Text: a[x], A: x, B: y})
+	}
+
+	// 反转为正序
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// Hunk 差异块
+type Hunk struct {
+	AStart, ALen int // 原文本起始行下标和行数
+	BStart, BLen int // 新文本起始行下标和行数
+	Ops          []Op
+}
+
+// Hunks 将编辑序列按上下文行数分组为差异块
+func Hunks(ops []Op, context int) []Hunk {
+	var hunks []Hunk
+	i := 0
+	for i < len(ops) {
+		// 找到下一处变更
+		for i < len(ops) && ops[i].Kind == Equal {
+			i++
+		}
+		if i >= len(ops) {
+			break
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// 向后扩展，直到连续相同行超过 2*context
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == Equal {
+				run++
+			}
+			if run >= len(ops) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		h := Hunk{AStart: ops[start].A, BStart: ops[start].B, Ops: ops[start:end]}
+		for _, op := range h.Ops {
+			if op.Kind != Insert {
+				h.ALen++
+			}
+			if op.Kind != Delete {
+				h.BLen++
+			}
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+// Unified 生成统一格式（unified diff）的差异文本，没有差异时返回空字符串
+func Unified(a, b, fromName, toName string) string {
+	if a == b {
+		return ""
+	}
+	hunks := Hunks(Lines(SplitLines(a), SplitLines(b)), 3)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n", fromName)
+	fmt.Fprintf(&sb, "+++ %s\n", toName)
+	for _, h := range hunks {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.AStart, h.ALen), hunkRange(h.BStart, h.BLen))
+		for _, op := range h.Ops {
+			switch op.Kind {
+			case Equal:
+				sb.WriteString(" ")
+			case Delete:
+				sb.WriteString("-")
+			case Insert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(op.Text)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// hunkRange 格式化差异块的行范围，行号从 1 开始
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// Colorize 为统一格式的差异文本添加终端颜色
+func Colorize(unified string) string {
+	lines := strings.SplitAfter(unified, "\n")
+	var sb strings.Builder
+	for _, line := range lines {
+		color := ""
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			color = terminal.Bold
+		case strings.HasPrefix(line, "@@"):
+			color = terminal.Cyan
+		case strings.HasPrefix(line, "+"):
+			color = terminal.Green
+		case strings.HasPrefix(line, "-"):
+			color = terminal.Red
+		}
+		if color == "" {
+			sb.WriteString(line)
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		sb.WriteString(color + text + terminal.Reset)
+		if len(text) < len(line) {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,156 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// apply 按编辑序列还原出原文本和新文本
+func apply(ops []Op) (a, b []string) {
+	for _, op := range ops {
+		if op.Kind != Insert {
+			a = append(a, op.Text)
+		}
+		if op.Kind != Delete {
+			b = append(b, op.Text)
+		}
+	}
+	return a, b
+}
+
+// edits 编辑序列中新增和删除的行数
+func edits(ops []Op) int {
+	n := 0
+	for _, op := range ops {
+		if op.Kind != Equal {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		edits int // 最短编辑序列的长度
+	}{
+		{name: "相同", a: "a\nb\nc", b: "a\nb\nc", edits: 0},
+		{name: "空文本新增", a: "", b: "a\nb", edits: 3},
+		{name: "中间插入", a: "a\nc", b: "a\nb\nc", edits: 1},
+		{name: "删除末尾", a: "a\nb\nc", b: "a\nb", edits: 1},
+		{name: "替换一行", a: "a\nb\nc", b: "a\nx\nc", edits: 2},
+		{name: "完全不同", a: "a\nb", b: "x\ny\nz", edits: 5},
+		{name: "重复行", a: "a\nb\na\nb", b: "b\na\nb\na", edits: 2},
+		{name: "交错修改", a: "a\nb\nc\nd\ne", b: "a\nc\nd\nx\ne\nf", edits: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := SplitLines(tt.a), SplitLines(tt.b)
+			ops := Lines(a, b)
+			gotA, gotB := apply(ops)
+			if strings.Join(gotA, "\n") != tt.a || strings.Join(gotB, "\n") != tt.b {
+				t.Fatalf("编辑序列无法还原文本: a=%q b=%q", gotA, gotB)
+			}
+			if n := edits(ops); n != tt.edits {
+				t.Errorf("编辑序列长度 = %d, 期望 %d", n, tt.edits)
+			}
+			for _, op := range ops {
+				if op.Kind != Insert && a[op.A] != op.Text {
+					t.Errorf("行下标 A=%d 的内容为 %q, 期望 %q", op.A, a[op.A], op.Text)
+				}
+				if op.Kind != Delete && b[op.B] != op.Text {
+					t.Errorf("行下标 B=%d 的内容为 %q, 期望 %q", op.B, b[op.B], op.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestLinesOverEditDistance(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxEditDistance; i++ {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+	a = append([]string{"head"}, append(a, "tail")...)
+	b = append([]string{"head"}, append(b, "tail")...)
+
+	ops := Lines(a, b)
+	gotA, gotB := apply(ops)
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+		t.Fatal("编辑序列无法还原文本")
+	}
+	if ops[0].Kind != Equal || ops[len(ops)-1].Kind != Equal {
+		t.Error("首尾相同的行应保留为 Equal")
+	}
+}
+
+func TestHunks(t *testing.T) {
+	lines := func(n int, change ...int) string {
+		var s []string
+		for i := 1; i <= n; i++ {
+			s = append(s, fmt.Sprintf("%d", i))
+		}
+		for _, c := range change {
+			s[c-1] = "x"
+		}
+		return strings.Join(s, "\n")
+	}
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    [][4]int // 每个差异块的 AStart, ALen, BStart, BLen
+	}{
+		{name: "没有差异", a: lines(5), b: lines(5), context: 3, want: nil},
+		{name: "单处修改", a: lines(10), b: lines(10, 5), context: 3, want: [][4]int{{1, 7, 1, 7}}},
+		{name: "上下文在开头截断", a: lines(10), b: lines(10, 1), context: 3, want: [][4]int{{0, 4, 0, 4}}},
+		{name: "相近的修改合并", a: lines(20), b: lines(20, 5, 10), context: 3, want: [][4]int{{1, 12, 1, 12}}},
+		{name: "相距较远的修改分开", a: lines(20), b: lines(20, 3, 15), context: 3, want: [][4]int{{0, 6, 0, 6}, {11, 7, 11, 7}}},
+		{name: "无上下文", a: lines(5), b: lines(5, 3), context: 0, want: [][4]int{{2, 1, 2, 1}}},
+		{name: "末尾新增", a: "a\nb", b: "a\nb\nc", context: 1, want: [][4]int{{1, 1, 1, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := Hunks(Lines(SplitLines(tt.a), SplitLines(tt.b)), tt.context)
+			var got [][4]int
+			for _, h := range hunks {
+				got = append(got, [4]int{h.AStart, h.ALen, h.BStart, h.BLen})
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Hunks() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "没有差异", a: "a\nb", b: "a\nb", want: ""},
+		{
+			name: "替换一行",
+			a:    "a\nb\nc",
+			b:    "a\nx\nc",
+			want: "--- a.go\n+++ b.go\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name: "空文本新增",
+			a:    "",
+			b:    "a",
+			want: "--- a.go\n+++ b.go\n@@ -1 +1 @@\n-\n+a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified(tt.a, tt.b, "a.go", "b.go"); got != tt.want {
+				t.Errorf("Unified() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConflictError 三方合并冲突
+type ConflictError struct {
+	StartLine int // 冲突在原文本中的起始行号，从 1 开始
+	EndLine   int // 冲突在原文本中的结束行号
+}
+
+func (e *ConflictError) Error() string {
+	if e.EndLine <= e.StartLine {
+		return fmt.Sprintf("第 %d 行附近的修改存在冲突", e.StartLine)
+	}
+	return fmt.Sprintf("第 %d-%d 行附近的修改存在冲突", e.StartLine, e.EndLine)
+}
+
+// change 相对于原文本的一处修改：将 [start, end) 行替换为 lines
+type change struct {
+	start, end int
+	lines      []string
+	ours       bool
+}
+
+// changes 将编辑序列转换为相对于原文本的修改列表
+func changes(ops []Op, ours bool) []change {
+	var result []change
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == Equal {
+			i++
+			continue
+		}
+		c := change{start: ops[i].A, end: ops[i].A, ours: ours}
+		for ; i < len(ops) && ops[i].Kind != Equal; i++ {
+			if ops[i].Kind == Delete {
+				c.end++
+			} else {
+				c.lines = append(c.lines, ops[i].Text)
+			}
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// Merge3 以 base 为共同祖先，合并 ours 和 theirs 两份修改
+// 两边修改的区域重叠或相邻且内容不同时返回 *ConflictError
+func Merge3(base, ours, theirs string) (string, error) {
+	if ours == theirs || theirs == base {
+		return ours, nil
+	}
+	if ours == base {
+		return theirs, nil
+	}
+
+	baseLines := SplitLines(base)
+	all := append(changes(Lines(baseLines, SplitLines(ours)), true),
+		changes(Lines(baseLines, SplitLines(theirs)), false)...)
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].start < all[j].start
+	})
+
+	var result []string
+	pos := 0
+	for i := 0; i < len(all); i++ {
+		c := all[i]
+		// 检查与后续修改是否重叠或相邻
+		if i+1 < len(all) {
+			next := all[i+1]
+			if next.start <= c.end && next.ours != c.ours {
+				if next.start == c.start && next.end == c.end && strings.Join(next.lines, "\n") == strings.Join(c.lines, "\n") {
+					// 两边做了相同的修改
+					i++
+				} else {
+					return "", &ConflictError{StartLine: c.start + 1, EndLine: max(c.end, next.end)}
+				}
+			}
+		}
+		result = append(result, baseLines[pos:c.start]...)
+		result = append(result, c.lines...)
+		pos = c.end
+	}
+	result = append(result, baseLines[pos:]...)
+	return strings.Join(result, "\n"), nil
+}
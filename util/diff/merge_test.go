@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	const base = "a\nb\nc\nd\ne"
+	tests := []struct {
+		name         string
+		base         string
+		ours, theirs string
+		want         string
+		conflict     *ConflictError // 期望的冲突，为 nil 时期望合并成功
+	}{
+		{name: "对方没有修改", base: base, ours: "a\nx\nc\nd\ne", theirs: base, want: "a\nx\nc\nd\ne"},
+		{name: "己方没有修改", base: base, ours: base, theirs: "a\nb\nc\nd\ny", want: "a\nb\nc\nd\ny"},
+		{name: "两边修改相同", base: base, ours: "a\nx\nc\nd\ne", theirs: "a\nx\nc\nd\ne", want: "a\nx\nc\nd\ne"},
+		{name: "修改不同的行", base: base, ours: "x\nb\nc\nd\ne", theirs: "a\nb\nc\nd\ny", want: "x\nb\nc\nd\ny"},
+		{name: "一边删除一边新增", base: base, ours: "a\nc\nd\ne", theirs: "a\nb\nc\nd\ne\nf", want: "a\nc\nd\ne\nf"},
+		{name: "不同位置插入", base: base, ours: "a\n1\nb\nc\nd\ne", theirs: "a\nb\nc\nd\n2\ne", want: "a\n1\nb\nc\nd\n2\ne"},
+		{name: "同一区域的相同修改", base: base, ours: "a\nx\nc\nd\ny", theirs: "a\nx\nc\nd\ne", want: "a\nx\nc\nd\ny"},
+		{
+			name: "同一行修改不同",
+			base: base, ours: "a\nx\nc\nd\ne", theirs: "a\ny\nc\nd\ne",
+			conflict: &ConflictError{StartLine: 2, EndLine: 2},
+		},
+		{
+			name: "相邻行的修改",
+			base: base, ours: "a\nx\nc\nd\ne", theirs: "a\nb\ny\nd\ne",
+			conflict: &ConflictError{StartLine: 2, EndLine: 3},
+		},
+		{
+			name: "同一位置插入不同内容",
+			base: base, ours: "a\nb\n1\nc\nd\ne", theirs: "a\nb\n2\nc\nd\ne",
+			conflict: &ConflictError{StartLine: 3, EndLine: 2},
+		},
+		{
+			name: "重叠区域的修改",
+			base: base, ours: "a\nx\ny\nd\ne", theirs: "a\nb\nz\nw\ne",
+			conflict: &ConflictError{StartLine: 2, EndLine: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge3(tt.base, tt.ours, tt.theirs)
+			if tt.conflict != nil {
+				var ce *ConflictError
+				if !errors.As(err, &ce) {
+					t.Fatalf("Merge3() 错误 = %v, 期望冲突", err)
+				}
+				if *ce != *tt.conflict {
+					t.Errorf("冲突 = %+v, 期望 %+v", *ce, *tt.conflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Merge3() 错误 = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Merge3() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConflictError(t *testing.T) {
+	tests := []struct {
+		err  ConflictError
+		want string
+	}{
+		{err: ConflictError{StartLine: 3, EndLine: 3}, want: "第 3 行附近的修改存在冲突"},
+		{err: ConflictError{StartLine: 3, EndLine: 2}, want: "第 3 行附近的修改存在冲突"},
+		{err: ConflictError{StartLine: 2, EndLine: 5}, want: "第 2-5 行附近的修改存在冲突"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, 期望 %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package errcode
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// 错误码，用于结构化输出中区分错误类型
+const (
+	InvalidArgument = "invalid_argument"  // 参数错误
+	Config          = "config_error"      // 配置错误，如未知的提供方、缺少 api key
+	Provider        = "provider_error"    // 调用大模型接口失败
+	NoStrategy      = "no_strategy"       // 没有能处理该事件的策略
+	Template        = "template_error"    // 渲染提示词模板失败
+	Extract         = "extract_failed"    // 无法从回答中提取代码
+	Patch           = "patch_failed"      // 结构化修改无法应用到文件
+	Validation      = "validation_failed" // 修改后的代码未通过校验
+	Conflict        = "conflict"          // 文件在补全期间被修改且无法合并
+	Hook            = "hook_failed"       // 配置的 before hook 执行失败
+	Canceled        = "canceled"          // 操作被取消
+	IO              = "io_error"          // 读写文件失败
+	Unknown         = "unknown"           // 未分类的错误
+)
+
+// Error 带错误码的错误
+type Error struct {
+	Code string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap 为错误附加错误码，err 为 nil 时返回 nil
+func Wrap(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Of 获取错误的错误码，未附加错误码时根据错误类型推断
+func Of(err error) string {
+	var coded *Error
+	var pathErr *fs.PathError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &coded):
+		return coded.Code
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.As(err, &pathErr):
+		return IO
+	}
+	return Unknown
+}
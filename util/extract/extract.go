@@ -0,0 +1,137 @@
+package extract
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/lang"
+	"regexp"
+	"strings"
+)
+
+// Block 回答中的一个 markdown 代码块
+type Block struct {
+	Lang   string // 代码块标记的语言，未标记时为空
+	Code   string // 代码内容，不含代码块标记
+	Closed bool   // 是否有结束标记
+	Line   int    // 开始标记所在行号，从 1 开始
+}
+
+var fenceOpen = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+
+// Blocks 解析回答中所有顶层的 markdown 代码块，支持 ``` 和 ~~~ 标记
+// 结束标记必须使用相同字符且长度不少于开始标记，缺少结束标记时代码块延续到回答末尾
+func Blocks(text string) []Block {
+	var blocks []Block
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, lang, ok := FenceOpen(strings.TrimSuffix(lines[i], "\r"))
+		if !ok {
+			continue
+		}
+		b := Block{Lang: lang, Line: i + 1}
+		var code []string
+		for i++; i < len(lines); i++ {
+			line := strings.TrimSuffix(lines[i], "\r")
+			if IsFenceClose(line, fence) {
+				b.Closed = true
+				break
+			}
+			code = append(code, line)
+		}
+		b.Code = strings.Join(code, "\n")
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// FenceOpen 判断是否为代码块开始标记，返回标记和小写的语言
+func FenceOpen(line string) (string, string, bool) {
+	m := fenceOpen.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], strings.ToLower(m[2]), true
+}
+
+// IsFenceClose 判断是否为对应开始标记的结束标记
+func IsFenceClose(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	rest := strings.TrimLeft(trimmed, fence[:1])
+	return len(trimmed)-len(rest) >= len(fence) && strings.TrimSpace(rest) == ""
+}
+
+// Code 从大模型的回答中提取需要写入文件的代码
+//   - 回答中没有代码块时，认为整个回答都是代码
+//   - 有多个代码块时，优先选择与文件语言 language 一致的代码块，其次是未标记语言的代码块
+//   - 无法确定唯一的代码块或代码为空时返回错误，避免把说明文字写入文件
+func Code(text, language string) (string, error) {
+	blocks := Blocks(text)
+	if len(blocks) == 0 {
+		code := trimBlankLines(text)
+		if code == "" {
+			return "", fmt.Errorf("大模型未返回任何代码")
+		}
+		return code, nil
+	}
+
+	candidates := blocks
+	if len(blocks) > 1 {
+		candidates = filter(blocks, func(b Block) bool { return lang.Match(b.Lang, language) })
+		if len(candidates) == 0 {
+			candidates = filter(blocks, func(b Block) bool { return b.Lang == "" })
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("回答中的 %d 个代码块都不是 %s 代码，无法确定需要写入的代码", len(blocks), languageName(language))
+	case 1:
+	default:
+		lines := make([]string, 0, len(candidates))
+		for _, b := range candidates {
+			lines = append(lines, fmt.Sprintf("%d", b.Line))
+		}
+		return "", fmt.Errorf("回答中包含 %d 个候选代码块（第 %s 行），无法确定需要写入的代码", len(candidates), strings.Join(lines, "、"))
+	}
+
+	code := trimBlankLines(candidates[0].Code)
+	if code == "" {
+		return "", fmt.Errorf("回答中的代码块为空")
+	}
+	return code, nil
+}
+
+func filter(blocks []Block, keep func(Block) bool) []Block {
+	var result []Block
+	for _, b := range blocks {
+		if keep(b) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// trimBlankLines 去掉首尾的空行和末尾空白，保留第一行代码的缩进
+func trimBlankLines(text string) string {
+	text = strings.TrimRight(text, " \t\r\n")
+	for {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 || strings.TrimSpace(text[:i]) != "" {
+			break
+		}
+		text = text[i+1:]
+	}
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return text
+}
+
+// languageName 文件语言名称，用于提示信息
+func languageName(language string) string {
+	if language == "" {
+		return "当前文件"
+	}
+	return language
+}
@@ -0,0 +1,75 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteFileAtomic 原子写入文件：先写入同目录下的临时文件，再重命名覆盖目标文件
+// 目标文件已存在时保留其权限，否则使用 perm
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("读取文件信息失败: %w", err)
+	}
+
+	// 临时文件必须与目标文件在同一目录，保证重命名是原子操作
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	tmpPath := tmp.Name()
+	// 成功重命名后临时文件已不存在，删除失败可以忽略
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("同步临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("设置文件权限失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("替换文件失败: %w", err)
+	}
+	return nil
+}
+
+const (
+	lockTimeout = 10 * time.Second // 等待锁的最长时间
+	lockStale   = 30 * time.Second // 锁文件超过该时间未释放时视为进程异常退出后遗留的锁
+)
+
+// Lock 通过独占创建锁文件 path 获取跨进程的锁，返回释放锁的函数
+// 锁已被占用时每隔 20ms 重试，超过 lockTimeout 返回错误；修改时间超过 lockStale 的锁文件视为遗留的锁并删除
+func Lock(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("创建锁文件失败: %w", err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("等待锁文件 %s 超时，如果没有其他 go-cli 进程正在运行，可以删除该文件", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
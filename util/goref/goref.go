@@ -0,0 +1,333 @@
+package goref
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Resolve 解析文件中 [start, end) 字节范围内的代码引用的类型、函数、常量和变量，返回它们在当前模块中的定义
+//   - fileText 为文件的当前内容，可以是编辑器中尚未保存的内容
+//   - 只解析当前包和同一模块内的包，标准库和第三方依赖不会被解析
+//   - 函数只保留签名，定义按在代码中首次引用的顺序排列，总长度超过 maxSize（字符数）时忽略后面的定义，为 0 时不限制
+func Resolve(filePath, fileText string, start, end, maxSize int) (string, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	r := newResolver(filepath.Dir(abs))
+
+	file, err := r.parse(abs, []byte(fileText))
+	if file == nil {
+		return "", fmt.Errorf("解析文件失败: %w", err)
+	}
+	files := []*ast.File{file}
+	others, err := r.parseDir(filepath.Dir(abs), file.Name.Name, strings.HasSuffix(abs, "_test.go"), abs)
+	if err != nil {
+		return "", err
+	}
+	files = append(files, others...)
+
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	conf := types.Config{Importer: r, Error: func(error) {}, FakeImportC: true}
+	// 代码不完整或引用了未解析的包时仍然继续，只使用能解析的部分
+	_, _ = conf.Check(r.importPath(filepath.Dir(abs)), r.fset, files, info)
+
+	tokFile := r.fset.File(file.Pos())
+	inSelection := func(pos token.Pos) bool {
+		if !pos.IsValid() || r.fset.File(pos) != tokFile {
+			return false
+		}
+		offset := tokFile.Offset(pos)
+		return offset >= start && offset < end
+	}
+
+	var objs []types.Object
+	seen := make(map[types.Object]bool)
+	add := func(obj types.Object) {
+		if obj == nil || seen[obj] || obj.Pkg() == nil || inSelection(obj.Pos()) || !r.inModule(obj.Pos()) {
+			return
+		}
+		seen[obj] = true
+		objs = append(objs, obj)
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || !inSelection(id.Pos()) {
+			return true
+		}
+		obj := info.Uses[id]
+		switch obj := obj.(type) {
+		case *types.TypeName, *types.Const:
+			if isPackageLevel(obj) {
+				add(obj)
+			}
+		case *types.Func:
+			add(obj)
+		case *types.Var:
+			// 局部变量、参数和字段只参考其类型的定义
+			if isPackageLevel(obj) {
+				add(obj)
+			}
+			if named := namedType(obj.Type()); named != nil {
+				add(named.Obj())
+			}
+		}
+		return true
+	})
+
+	var b strings.Builder
+	done := make(map[string]bool)
+	for _, obj := range objs {
+		def, key := r.definition(obj)
+		if def == "" || done[key] {
+			continue
+		}
+		done[key] = true
+		pos := r.fset.Position(obj.Pos())
+		entry := fmt.Sprintf("// %s:%d\n%s\n\n", r.relPath(pos.Filename), pos.Line, def)
+		if maxSize > 0 && len([]rune(b.String()))+len([]rune(entry)) > maxSize {
+			break
+		}
+		b.WriteString(entry)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// isPackageLevel 是否为包级别的声明
+func isPackageLevel(obj types.Object) bool {
+	return obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope()
+}
+
+// namedType 去掉指针、切片、数组、map、channel 后的命名类型
+func namedType(t types.Type) *types.Named {
+	for {
+		switch u := t.(type) {
+		case *types.Named:
+			return u
+		case *types.Alias:
+			t = types.Unalias(u)
+		case *types.Pointer:
+			t = u.Elem()
+		case *types.Slice:
+			t = u.Elem()
+		case *types.Array:
+			t = u.Elem()
+		case *types.Map:
+			t = u.Elem()
+		case *types.Chan:
+			t = u.Elem()
+		default:
+			return nil
+		}
+	}
+}
+
+// resolver 解析当前模块中的包，模块外的包不解析
+type resolver struct {
+	fset    *token.FileSet
+	modRoot string                    // 模块根目录，不在模块中时为当前包目录
+	modPath string                    // 模块路径，不在模块中时为空
+	pkgs    map[string]*types.Package // 已解析的包，正在解析的包为 nil
+	files   map[string]*ast.File      // 已解析的文件
+	sources map[string][]byte         // 已解析文件的内容
+}
+
+func newResolver(dir string) *resolver {
+	r := &resolver{
+		fset:    token.NewFileSet(),
+		modRoot: dir,
+		pkgs:    make(map[string]*types.Package),
+		files:   make(map[string]*ast.File),
+		sources: make(map[string][]byte),
+	}
+	if root, path := findModule(dir); root != "" {
+		r.modRoot, r.modPath = root, path
+	}
+	return r
+}
+
+// findModule 向上查找 go.mod，返回模块根目录和模块路径
+func findModule(dir string) (root, path string) {
+	for {
+		f, err := os.Open(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) == 2 && fields[0] == "module" {
+					return dir, strings.Trim(fields[1], `"`)
+				}
+			}
+			return dir, ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// importPath 目录对应的导入路径
+func (r *resolver) importPath(dir string) string {
+	rel, err := filepath.Rel(r.modRoot, dir)
+	if err != nil || r.modPath == "" {
+		return filepath.Base(dir)
+	}
+	if rel == "." {
+		return r.modPath
+	}
+	return r.modPath + "/" + filepath.ToSlash(rel)
+}
+
+// inModule 声明是否位于当前模块中
+func (r *resolver) inModule(pos token.Pos) bool {
+	filename := r.fset.Position(pos).Filename
+	return filename != "" && strings.HasPrefix(filename, r.modRoot+string(filepath.Separator))
+}
+
+// relPath 相对于模块根目录的路径
+func (r *resolver) relPath(filename string) string {
+	if rel, err := filepath.Rel(r.modRoot, filename); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filename
+}
+
+func (r *resolver) Import(path string) (*types.Package, error) {
+	return r.ImportFrom(path, "", 0)
+}
+
+// ImportFrom 从源码解析当前模块中的包，模块外的包返回错误，引用它们的代码不会被解析
+func (r *resolver) ImportFrom(path, _ string, _ types.ImportMode) (*types.Package, error) {
+	if pkg, ok := r.pkgs[path]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("循环导入 %s", path)
+		}
+		return pkg, nil
+	}
+	if r.modPath == "" || path != r.modPath && !strings.HasPrefix(path, r.modPath+"/") {
+		return nil, fmt.Errorf("不解析模块外的包 %s", path)
+	}
+	r.pkgs[path] = nil
+	dir := filepath.Join(r.modRoot, filepath.FromSlash(strings.TrimPrefix(path, r.modPath)))
+	files, err := r.parseDir(dir, "", false, "")
+	if err != nil {
+		delete(r.pkgs, path)
+		return nil, err
+	}
+	conf := types.Config{Importer: r, Error: func(error) {}, FakeImportC: true}
+	pkg, _ := conf.Check(path, r.fset, files, nil)
+	r.pkgs[path] = pkg
+	return pkg, nil
+}
+
+// parseDir 解析目录中符合当前构建条件的文件，pkgName 为空时使用第一个文件的包名，skip 为已解析的文件
+func (r *resolver) parseDir(dir, pkgName string, tests bool, skip string) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录失败: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || !tests && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []*ast.File
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if path == skip {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		f, _ := r.parse(path, src)
+		if f == nil {
+			continue
+		}
+		if pkgName == "" {
+			pkgName = f.Name.Name
+		}
+		if f.Name.Name == pkgName {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func (r *resolver) parse(path string, src []byte) (*ast.File, error) {
+	f, err := parser.ParseFile(r.fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if f != nil {
+		r.files[path] = f
+		r.sources[path] = src
+	}
+	return f, err
+}
+
+// definition 声明的源码，函数只保留签名，key 用于去重同一个声明中的多个对象
+func (r *resolver) definition(obj types.Object) (def, key string) {
+	filename := r.fset.Position(obj.Pos()).Filename
+	file, src := r.files[filename], r.sources[filename]
+	if file == nil {
+		return "", ""
+	}
+	text := func(from, to token.Pos) string {
+		return string(src[r.fset.Position(from).Offset:r.fset.Position(to).Offset])
+	}
+	withDoc := func(doc *ast.CommentGroup, body string) string {
+		if doc == nil {
+			return body
+		}
+		return text(doc.Pos(), doc.End()) + "\n" + body
+	}
+
+	pos := obj.Pos()
+	for _, decl := range file.Decls {
+		if pos < decl.Pos() || pos >= decl.End() {
+			continue
+		}
+		key = fmt.Sprintf("%s:%d", filename, decl.Pos())
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			return withDoc(d.Doc, text(d.Pos(), d.Type.End())), key
+		case *ast.GenDecl:
+			// 未分组的声明和常量组完整保留，常量组中可能依赖 iota
+			if !d.Lparen.IsValid() || d.Tok == token.CONST {
+				return withDoc(d.Doc, text(d.Pos(), d.End())), key
+			}
+			for _, spec := range d.Specs {
+				if pos < spec.Pos() || pos >= spec.End() {
+					continue
+				}
+				key = fmt.Sprintf("%s:%d", filename, spec.Pos())
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					return withDoc(s.Doc, "type "+text(s.Pos(), s.End())), key
+				case *ast.ValueSpec:
+					return withDoc(s.Doc, d.Tok.String()+" "+text(s.Pos(), s.End())), key
+				}
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,253 @@
+package journal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/config"
+	"github.com/MenciusCheng/go-cli/util/fsutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	MaxEntries   = 100      // 最多保留的编辑记录数
+	MaxEntrySize = 1 << 20  // 单条记录编辑前后内容的最大总字节数，超过时不保存内容，无法撤销
+	MaxTotalSize = 32 << 20 // 所有记录内容的最大总字节数，超过时删除最早的记录
+)
+
+// Entry 一次文件编辑记录
+type Entry struct {
+	ID       int       `json:"id"`       // 记录编号，递增
+	Path     string    `json:"path"`     // 文件绝对路径
+	Original []byte    `json:"original"` // 编辑前的内容
+	New      []byte    `json:"new"`      // 编辑后的内容
+	Prompt   string    `json:"prompt"`   // 用户输入的提示信息
+	Model    string    `json:"model"`    // 使用的模型
+	Time     time.Time `json:"time"`     // 编辑时间
+	Undone   bool      `json:"undone"`   // 是否已撤销
+	Large    bool      `json:"large"`    // 内容超过 MaxEntrySize，未保存编辑前后的内容，无法撤销
+	Created  bool      `json:"created"`  // 编辑前文件不存在，撤销时删除文件
+}
+
+// Journal 编辑记录
+type Journal struct {
+	path string
+}
+
+// New 创建编辑记录，保存在用户状态目录下
+func New() *Journal {
+	return &Journal{path: filepath.Join(config.StateDir(), "edits.json")}
+}
+
+// Path 编辑记录文件路径
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// List 获取所有编辑记录，按时间先后排列
+func (j *Journal) List() ([]Entry, error) {
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取编辑记录失败: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("解析编辑记录失败: %w", err)
+	}
+	return entries, nil
+}
+
+// lock 获取编辑记录的锁，读取、修改和写入记录期间持有，避免同时运行的多个进程丢失记录
+func (j *Journal) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return nil, fmt.Errorf("创建状态目录失败: %w", err)
+	}
+	return fsutil.Lock(j.path + ".lock")
+}
+
+// save 写入编辑记录，超过 MaxEntries 条或 MaxTotalSize 字节时删除最早的记录
+func (j *Journal) save(entries []Entry) error {
+	if len(entries) > MaxEntries {
+		entries = entries[len(entries)-MaxEntries:]
+	}
+	total := 0
+	for _, e := range entries {
+		total += len(e.Original) + len(e.New)
+	}
+	for len(entries) > 1 && total > MaxTotalSize {
+		total -= len(entries[0].Original) + len(entries[0].New)
+		entries = entries[1:]
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("序列化编辑记录失败: %w", err)
+	}
+	if err := os.WriteFile(j.path, data, 0600); err != nil {
+		return fmt.Errorf("写入编辑记录失败: %w", err)
+	}
+	return nil
+}
+
+// Record 记录一次编辑，同时清空可重做的记录，编辑前后的内容超过 MaxEntrySize 时只记录编辑信息
+func (j *Journal) Record(e Entry) error {
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := j.List()
+	if err != nil {
+		return err
+	}
+	e.ID = 1
+	for _, old := range entries {
+		e.ID = max(e.ID, old.ID+1)
+	}
+	// 新的编辑会使已撤销的记录无法重做
+	kept := entries[:0]
+	for _, old := range entries {
+		if !old.Undone {
+			kept = append(kept, old)
+		}
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Undone = false
+	if len(e.Original)+len(e.New) > MaxEntrySize {
+		e.Original, e.New, e.Large = nil, nil, true
+	}
+	return j.save(append(kept, e))
+}
+
+// Undo 按时间倒序撤销最近 n 次编辑，遇到文件已被修改的记录时停止并返回错误
+func (j *Journal) Undo(n int) ([]Entry, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := j.List()
+	if err != nil {
+		return nil, err
+	}
+	var undone []Entry
+	for i := len(entries) - 1; i >= 0 && len(undone) < n; i-- {
+		e := &entries[i]
+		if e.Undone {
+			continue
+		}
+		var err error
+		switch {
+		case e.Large:
+			err = fmt.Errorf("文件超过 %d 字节，未保存编辑前的内容", MaxEntrySize)
+		case e.Created:
+			err = remove(e.Path, e.New)
+		default:
+			err = restore(e.Path, e.New, e.Original)
+		}
+		if err != nil {
+			if saveErr := j.save(entries); saveErr != nil {
+				return undone, saveErr
+			}
+			return undone, fmt.Errorf("撤销编辑 #%d 失败: %w", e.ID, err)
+		}
+		e.Undone = true
+		undone = append(undone, *e)
+	}
+	if len(undone) == 0 {
+		return nil, fmt.Errorf("没有可撤销的编辑")
+	}
+	return undone, j.save(entries)
+}
+
+// Redo 重做最早一次被撤销的编辑
+func (j *Journal) Redo() (*Entry, error) {
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := j.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		e := &entries[i]
+		if !e.Undone {
+			continue
+		}
+		var err error
+		if e.Created {
+			err = create(e.Path, e.New)
+		} else {
+			err = restore(e.Path, e.Original, e.New)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("重做编辑 #%d 失败: %w", e.ID, err)
+		}
+		e.Undone = false
+		redone := *e
+		return &redone, j.save(entries)
+	}
+	return nil, fmt.Errorf("没有可重做的编辑")
+}
+
+// restore 确认文件当前内容为 expected 后写入 content，文件已被修改时拒绝写入
+func restore(path string, expected, content []byte) error {
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	if !bytes.Equal(current, expected) {
+		return fmt.Errorf("文件 %s 在编辑后已被修改（当前 %s，预期 %s），为避免覆盖修改已拒绝操作",
+			path, Hash(current), Hash(expected))
+	}
+	if err := fsutil.WriteFileAtomic(path, content, 0644); err != nil {
+		return err
+	}
+	return nil
+}
+
+// remove 确认文件当前内容为 expected 后删除文件，用于撤销新建的文件
+func remove(path string, expected []byte) error {
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	if !bytes.Equal(current, expected) {
+		return fmt.Errorf("文件 %s 在创建后已被修改（当前 %s，预期 %s），为避免丢失修改已拒绝删除",
+			path, Hash(current), Hash(expected))
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("删除文件失败: %w", err)
+	}
+	return nil
+}
+
+// create 确认文件不存在后写入 content，用于重做新建的文件
+func create(path string, content []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("文件 %s 已存在，为避免覆盖已拒绝操作", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	return fsutil.WriteFileAtomic(path, content, 0644)
+}
+
+// Hash 计算内容的短哈希，用于展示
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
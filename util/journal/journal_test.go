@@ -0,0 +1,191 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// record 写入编辑后的文件并记录编辑，original 为 nil 时表示新建文件
+func record(t *testing.T, j *Journal, path string, original, content []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record(Entry{Path: path, Original: original, New: content, Created: original == nil}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile 读取文件内容，文件不存在时返回 nil
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name     string
+		original []byte // 编辑前的内容，为 nil 时表示新建文件
+		modified []byte // 编辑后再次写入的内容，为 nil 时不修改
+		wantErr  bool
+		want     []byte // 撤销后的文件内容，为 nil 时期望文件不存在
+	}{
+		{name: "撤销修改", original: []byte("old"), want: []byte("old")},
+		{name: "编辑后文件被修改", original: []byte("old"), modified: []byte("changed"), wantErr: true, want: []byte("changed")},
+		{name: "撤销新建", want: nil},
+		{name: "新建后文件被修改", modified: []byte("changed"), wantErr: true, want: []byte("changed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			j := &Journal{path: filepath.Join(dir, "edits.json")}
+			path := filepath.Join(dir, "main.go")
+			record(t, j, path, tt.original, []byte("new"))
+			if tt.modified != nil {
+				if err := os.WriteFile(path, tt.modified, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := j.Undo(1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Undo() 错误 = %v, 期望错误 %v", err, tt.wantErr)
+			}
+			if got := readFile(t, path); string(got) != string(tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("文件内容 = %q, 期望 %q", got, tt.want)
+			}
+			entries, err := j.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entries[0].Undone == tt.wantErr {
+				t.Errorf("记录的撤销状态 = %v, 期望 %v", entries[0].Undone, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUndoStopsAtModifiedFile(t *testing.T) {
+	dir := t.TempDir()
+	j := &Journal{path: filepath.Join(dir, "edits.json")}
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	record(t, j, a, []byte("a0"), []byte("a1"))
+	record(t, j, b, []byte("b0"), []byte("b1"))
+	record(t, j, b, []byte("b1"), []byte("b2"))
+	if err := os.WriteFile(a, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	undone, err := j.Undo(3)
+	if err == nil {
+		t.Fatal("Undo() 期望在文件被修改的记录处返回错误")
+	}
+	if len(undone) != 2 {
+		t.Errorf("撤销了 %d 次编辑, 期望 2", len(undone))
+	}
+	if got := readFile(t, b); string(got) != "b0" {
+		t.Errorf("b.go 内容 = %q, 期望 %q", got, "b0")
+	}
+	if got := readFile(t, a); string(got) != "changed" {
+		t.Errorf("a.go 内容 = %q, 期望保留修改", got)
+	}
+	entries, err := j.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{false, true, true} {
+		if entries[i].Undone != want {
+			t.Errorf("记录 #%d 撤销状态 = %v, 期望 %v", entries[i].ID, entries[i].Undone, want)
+		}
+	}
+}
+
+func TestUndoLargeEntry(t *testing.T) {
+	dir := t.TempDir()
+	j := &Journal{path: filepath.Join(dir, "edits.json")}
+	path := filepath.Join(dir, "main.go")
+	record(t, j, path, make([]byte, MaxEntrySize), []byte("new"))
+
+	if _, err := j.Undo(1); err == nil {
+		t.Fatal("Undo() 期望无法撤销未保存内容的记录")
+	}
+	if got := readFile(t, path); string(got) != "new" {
+		t.Errorf("文件内容 = %q, 期望保持不变", got)
+	}
+}
+
+func TestRedo(t *testing.T) {
+	tests := []struct {
+		name     string
+		original []byte // 编辑前的内容，为 nil 时表示新建文件
+		modified []byte // 撤销后再次写入的内容，为 nil 时不修改
+		wantErr  bool
+		want     []byte // 重做后的文件内容
+	}{
+		{name: "重做修改", original: []byte("old"), want: []byte("new")},
+		{name: "撤销后文件被修改", original: []byte("old"), modified: []byte("changed"), wantErr: true, want: []byte("changed")},
+		{name: "重做新建", want: []byte("new")},
+		{name: "撤销新建后文件被重新创建", modified: []byte("changed"), wantErr: true, want: []byte("changed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			j := &Journal{path: filepath.Join(dir, "edits.json")}
+			path := filepath.Join(dir, "main.go")
+			record(t, j, path, tt.original, []byte("new"))
+			if _, err := j.Undo(1); err != nil {
+				t.Fatal(err)
+			}
+			if tt.modified != nil {
+				if err := os.WriteFile(path, tt.modified, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := j.Redo()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Redo() 错误 = %v, 期望错误 %v", err, tt.wantErr)
+			}
+			if got := readFile(t, path); string(got) != string(tt.want) {
+				t.Errorf("文件内容 = %q, 期望 %q", got, tt.want)
+			}
+			entries, err := j.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entries[0].Undone != tt.wantErr {
+				t.Errorf("记录的撤销状态 = %v, 期望 %v", entries[0].Undone, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecordClearsRedo(t *testing.T) {
+	dir := t.TempDir()
+	j := &Journal{path: filepath.Join(dir, "edits.json")}
+	path := filepath.Join(dir, "main.go")
+	record(t, j, path, []byte("v0"), []byte("v1"))
+	if _, err := j.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	record(t, j, path, []byte("v0"), []byte("v2"))
+
+	if _, err := j.Redo(); err == nil {
+		t.Fatal("Redo() 期望新的编辑后没有可重做的记录")
+	}
+	entries, err := j.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != 2 {
+		t.Errorf("编辑记录 = %+v, 期望只保留编号为 2 的记录", entries)
+	}
+}
@@ -0,0 +1,216 @@
+package lang
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 常用的语言名称，同时作为 markdown 代码块的语言标记和规则模板的语言后缀
+const (
+	Go     = "go"
+	SQL    = "sql"
+	Shell  = "shell"
+	Python = "python"
+	Java   = "java"
+	YAML   = "yaml"
+)
+
+// aliases 各语言在代码块语言标记、vim filetype、解释器名称中的别名，第一个为语言名称
+var aliases = [][]string{
+	{Go, "golang"},
+	{Python, "py", "python3", "python2"},
+	{"javascript", "js", "jsx", "node", "nodejs"},
+	{"typescript", "ts", "tsx"},
+	{Java},
+	{"kotlin", "kt"},
+	{"rust", "rs"},
+	{"c", "h"},
+	{"cpp", "c++", "cc", "cxx", "hpp"},
+	{"ruby", "rb"},
+	{"php"},
+	{"perl", "pl"},
+	{"swift"},
+	{SQL, "mysql", "postgresql", "postgres", "pgsql", "plsql", "sqlite"},
+	{Shell, "sh", "bash", "zsh", "dash", "ksh"},
+	{YAML, "yml"},
+	{"json", "jsonc"},
+	{"xml"},
+	{"html", "htm"},
+	{"css"},
+	{"proto", "protobuf"},
+	{"markdown", "md"},
+	{"dockerfile", "docker"},
+	{"makefile", "make"},
+}
+
+// extensions 文件扩展名对应的语言
+var extensions = map[string]string{
+	".go":    Go,
+	".py":    Python,
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".java":  Java,
+	".kt":    "kotlin",
+	".rs":    "rust",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cc":    "cpp",
+	".hpp":   "cpp",
+	".rb":    "ruby",
+	".php":   "php",
+	".pl":    "perl",
+	".swift": "swift",
+	".sql":   SQL,
+	".sh":    Shell,
+	".bash":  Shell,
+	".zsh":   Shell,
+	".yaml":  YAML,
+	".yml":   YAML,
+	".json":  "json",
+	".xml":   "xml",
+	".html":  "html",
+	".css":   "css",
+	".proto": "proto",
+	".md":    "markdown",
+}
+
+// fileNames 没有扩展名的常见文件
+var fileNames = map[string]string{
+	"dockerfile":    "dockerfile",
+	"makefile":      "makefile",
+	"gnumakefile":   "makefile",
+	".bashrc":       Shell,
+	".zshrc":        Shell,
+	".profile":      Shell,
+	".bash_profile": Shell,
+}
+
+// byAlias 别名对应的语言名称
+var byAlias = func() map[string]string {
+	m := make(map[string]string)
+	for _, names := range aliases {
+		for _, name := range names {
+			m[name] = names[0]
+		}
+	}
+	return m
+}()
+
+// Normalize 将代码块语言标记、vim filetype、解释器名称等别名转换为语言名称，未知的别名转为小写后原样返回
+func Normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if l, ok := byAlias[name]; ok {
+		return l
+	}
+	return name
+}
+
+// Match 判断别名是否属于该语言
+func Match(alias, language string) bool {
+	return alias != "" && language != "" && Normalize(alias) == Normalize(language)
+}
+
+// FromPath 根据文件名和扩展名判断语言，未知的扩展名去掉点后原样返回，无法判断时返回空字符串
+func FromPath(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	if l, ok := fileNames[base]; ok {
+		return l
+	}
+	ext := filepath.Ext(base)
+	if l, ok := extensions[ext]; ok {
+		return l
+	}
+	if len(ext) > 1 && ext != base {
+		return ext[1:]
+	}
+	return ""
+}
+
+// Detect 判断文件的语言，依次根据 modeline、文件名和扩展名、shebang 判断，无法判断时返回空字符串
+// content 为文件内容，可以只包含开头和结尾的部分
+func Detect(path, content string) string {
+	if l := fromModeline(content); l != "" {
+		return l
+	}
+	if l := FromPath(path); l != "" {
+		return l
+	}
+	return fromShebang(content)
+}
+
+// modelineLines 在文件开头和结尾查找 modeline 的行数，与 vim 默认的 modelines 一致
+const modelineLines = 5
+
+var (
+	// vimModeline 如 vim: set ft=sql: 或 vi: filetype=sh
+	vimModeline = regexp.MustCompile(`(?:^|\s)(?:vim?|ex):.*?\b(?:ft|filetype|syntax)=([\w+-]+)`)
+	// emacsModeline 如 -*- mode: sql; coding: utf-8 -*-
+	emacsModeline = regexp.MustCompile(`-\*-.*?\bmode:\s*([\w+-]+).*?-\*-`)
+	// emacsShortModeline 如 -*- sql -*-
+	emacsShortModeline = regexp.MustCompile(`-\*-\s*([\w+-]+)\s*-\*-`)
+)
+
+// fromModeline 根据 vim 或 emacs 的 modeline 判断语言
+func fromModeline(content string) string {
+	lines := strings.Split(content, "\n")
+	check := lines
+	if len(lines) > 2*modelineLines {
+		check = append(lines[:modelineLines:modelineLines], lines[len(lines)-modelineLines:]...)
+	}
+	for _, line := range check {
+		if m := vimModeline.FindStringSubmatch(line); m != nil {
+			return Normalize(m[1])
+		}
+	}
+	// emacs 只在第一行，有 shebang 时在第二行
+	for _, line := range lines[:min(2, len(lines))] {
+		if m := emacsModeline.FindStringSubmatch(line); m != nil {
+			return Normalize(m[1])
+		}
+		if m := emacsShortModeline.FindStringSubmatch(line); m != nil {
+			return Normalize(m[1])
+		}
+	}
+	return ""
+}
+
+// interpreterVersion 解释器名称末尾的版本号，如 python3.11 中的 3.11
+var interpreterVersion = regexp.MustCompile(`[\d.]+$`)
+
+// fromShebang 根据第一行的 #! 解释器判断语言
+func fromShebang(content string) string {
+	line, _, _ := strings.Cut(content, "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// #!/usr/bin/env -S python3 -u
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				interpreter = filepath.Base(f)
+				break
+			}
+		}
+	}
+	if interpreter == "" {
+		return ""
+	}
+	if l, ok := byAlias[interpreter]; ok {
+		return l
+	}
+	if l, ok := byAlias[interpreterVersion.ReplaceAllString(interpreter, "")]; ok {
+		return l
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level 日志级别
+type Level int
+
+const (
+	LevelDebug Level = iota // 调试信息，如发送给大模型的提示词
+	LevelInfo               // 执行过程的提示信息
+	LevelWarn               // 不影响结果的警告
+	LevelError              // 错误
+)
+
+// LevelNames 支持的日志级别名称
+var LevelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return LevelNames[l]
+}
+
+// ParseLevel 解析日志级别名称，不区分大小写
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "warning" {
+		name = "warn"
+	}
+	for i, n := range LevelNames {
+		if n == name {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("不支持的日志级别 %q，可选值: %s", name, strings.Join(LevelNames, ", "))
+}
+
+var (
+	mu      sync.Mutex
+	level             = LevelInfo
+	console io.Writer = os.Stderr
+	file    *os.File
+)
+
+// SetLevel 设置输出到标准错误的日志级别
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+}
+
+// GetLevel 获取输出到标准错误的日志级别
+func GetLevel() Level {
+	mu.Lock()
+	defer mu.Unlock()
+	return level
+}
+
+// SetOutput 设置控制台日志的输出，默认为标准错误
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	console = w
+}
+
+// OpenFile 将日志追加写入文件，文件中始终记录 debug 及以上级别的日志，便于反馈问题时附带
+func OpenFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file = f
+	fmt.Fprintf(file, "%s [info] ===== %s =====\n", time.Now().Format("2006-01-02 15:04:05.000"), strings.Join(os.Args, " "))
+	return nil
+}
+
+// Close 关闭日志文件
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Enabled 该级别的日志是否会输出到控制台或日志文件
+func Enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return l >= level || file != nil
+}
+
+// Debugf 输出调试信息
+func Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, "", format, args...)
+}
+
+// Infof 输出提示信息
+func Infof(format string, args ...interface{}) {
+	logf(LevelInfo, "", format, args...)
+}
+
+// Warnf 输出警告，控制台中以 "警告: " 开头
+func Warnf(format string, args ...interface{}) {
+	logf(LevelWarn, "警告: ", format, args...)
+}
+
+// Errorf 输出错误，控制台中以 "错误: " 开头
+func Errorf(format string, args ...interface{}) {
+	logf(LevelError, "错误: ", format, args...)
+}
+
+// logf 控制台按原样输出消息，日志文件中每条消息带时间和级别
+func logf(l Level, prefix, format string, args ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l < level && file == nil {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if l >= level {
+		fmt.Fprint(console, prefix+msg)
+		if prefix != "" && !strings.HasSuffix(msg, "\n") {
+			fmt.Fprintln(console)
+		}
+	}
+	if file != nil {
+		text := strings.Trim(msg, "\n")
+		if text == "" {
+			return
+		}
+		fmt.Fprintf(file, "%s [%s] %s\n", time.Now().Format("2006-01-02 15:04:05.000"), l, text)
+	}
+}
@@ -0,0 +1,122 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/MenciusCheng/go-cli/util/terminal"
+)
+
+// syntax 语言的高亮规则
+type syntax struct {
+	keywords map[string]bool
+	comments []string // 单行注释前缀
+}
+
+func newSyntax(keywords string, comments ...string) *syntax {
+	s := &syntax{keywords: make(map[string]bool), comments: comments}
+	for _, k := range strings.Fields(keywords) {
+		s.keywords[k] = true
+	}
+	return s
+}
+
+var (
+	goSyntax     = newSyntax("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false iota", "//")
+	pySyntax     = newSyntax("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False self", "#")
+	jsSyntax     = newSyntax("async await break case catch class const continue default delete do else export extends finally for from function if import in instanceof interface let new null of return super switch this throw try type typeof undefined var void while yield true false", "//")
+	javaSyntax   = newSyntax("abstract boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long new null package private protected public return short static super switch synchronized this throw throws try void volatile while true false fun val var when object", "//")
+	rustSyntax   = newSyntax("as async await break const continue crate else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while", "//")
+	cSyntax      = newSyntax("auto break case char class const continue default delete do double else enum extern float for if include define inline int long namespace new nullptr private protected public return short signed sizeof static struct switch template this typedef union unsigned using virtual void volatile while true false", "//")
+	shellSyntax  = newSyntax("if then else elif fi for while until do done case esac function in return local export echo exit", "#")
+	sqlSyntax    = newSyntax("select from where and or not insert into values update set delete create table alter drop index primary key foreign references join left right inner outer on group by order having limit offset as distinct union all null is in like between case when then else end count sum avg min max", "--")
+	yamlSyntax   = newSyntax("true false null yes no", "#")
+	plainSyntax  = newSyntax("", "//", "#")
+	syntaxByLang = map[string]*syntax{
+		"go": goSyntax, "golang": goSyntax,
+		"python": pySyntax, "py": pySyntax, "python3": pySyntax,
+		"javascript": jsSyntax, "js": jsSyntax, "jsx": jsSyntax, "typescript": jsSyntax, "ts": jsSyntax, "tsx": jsSyntax,
+		"java": javaSyntax, "kotlin": javaSyntax, "kt": javaSyntax,
+		"rust": rustSyntax, "rs": rustSyntax,
+		"c": cSyntax, "cpp": cSyntax, "c++": cSyntax, "h": cSyntax,
+		"shell": shellSyntax, "sh": shellSyntax, "bash": shellSyntax, "zsh": shellSyntax,
+		"sql": sqlSyntax, "mysql": sqlSyntax, "postgresql": sqlSyntax,
+		"yaml": yamlSyntax, "yml": yamlSyntax,
+	}
+)
+
+// Highlight 对一行代码进行语法高亮：关键字、字符串、数字和单行注释
+// 不认识的语言只高亮字符串、数字和常见注释
+func Highlight(line, lang string) string {
+	s, ok := syntaxByLang[lang]
+	if !ok {
+		s = plainSyntax
+	}
+	caseInsensitive := s == sqlSyntax
+
+	var b strings.Builder
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		rest := string(runes[i:])
+
+		if prefix := s.commentPrefix(rest); prefix != "" && (i == 0 || !isWord(runes[i-1])) {
+			b.WriteString(terminal.Dim + rest + terminal.Reset)
+			break
+		}
+
+		switch {
+		case r == '"' || r == '\'' || r == '`':
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' && r != '`' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, len(runes))
+			b.WriteString(terminal.Green + string(runes[i:j]) + terminal.Reset)
+			i = j
+		case unicode.IsDigit(r) && (i == 0 || !isWord(runes[i-1])):
+			j := i
+			for j < len(runes) && (isWord(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			b.WriteString(terminal.Yellow + string(runes[i:j]) + terminal.Reset)
+			i = j
+		case isWord(r):
+			j := i
+			for j < len(runes) && isWord(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			key := word
+			if caseInsensitive {
+				key = strings.ToLower(word)
+			}
+			if s.keywords[key] {
+				b.WriteString(terminal.Blue + word + terminal.Reset)
+			} else {
+				b.WriteString(word)
+			}
+			i = j
+		default:
+			b.WriteRune(r)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func (s *syntax) commentPrefix(text string) string {
+	for _, prefix := range s.comments {
+		if strings.HasPrefix(text, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
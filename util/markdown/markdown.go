@@ -0,0 +1,125 @@
+package markdown
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/MenciusCheng/go-cli/util/extract"
+	"github.com/MenciusCheng/go-cli/util/terminal"
+)
+
+// Renderer 流式 markdown 终端渲染器
+// 大模型的回答按 token 流式到达，渲染器按行缓冲，每收到完整的一行就渲染输出
+type Renderer struct {
+	w     io.Writer
+	line  strings.Builder
+	fence string // 当前代码块的开始标记，为空表示不在代码块中
+	lang  string // 当前代码块的语言
+}
+
+// NewRenderer 创建渲染器，渲染结果写入 w
+func NewRenderer(w io.Writer) *Renderer {
+	return &Renderer{w: w}
+}
+
+// Write 写入一段流式输出，实现 io.Writer
+func (r *Renderer) Write(p []byte) (int, error) {
+	r.WriteString(string(p))
+	return len(p), nil
+}
+
+// WriteString 写入一段流式输出，遇到换行时渲染完整的行
+func (r *Renderer) WriteString(s string) {
+	for {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			r.line.WriteString(s)
+			return
+		}
+		r.line.WriteString(s[:i])
+		r.renderLine(r.line.String())
+		io.WriteString(r.w, "\n")
+		r.line.Reset()
+		s = s[i+1:]
+	}
+}
+
+// Flush 渲染最后不完整的一行，回答结束时调用
+func (r *Renderer) Flush() {
+	if r.line.Len() > 0 {
+		r.renderLine(r.line.String())
+		r.line.Reset()
+	}
+}
+
+var (
+	headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listLine    = regexp.MustCompile(`^(\s*)([-*+])\s+(.*)$`)
+	orderedLine = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	ruleLine    = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+)
+
+func (r *Renderer) renderLine(line string) {
+	line = strings.TrimSuffix(line, "\r")
+	if r.fence != "" {
+		if extract.IsFenceClose(line, r.fence) {
+			r.fence, r.lang = "", ""
+			io.WriteString(r.w, terminal.Dim+line+terminal.Reset)
+			return
+		}
+		io.WriteString(r.w, Highlight(line, r.lang))
+		return
+	}
+
+	if fence, lang, ok := extract.FenceOpen(line); ok {
+		r.fence, r.lang = fence, lang
+		io.WriteString(r.w, terminal.Dim+line+terminal.Reset)
+		return
+	}
+
+	var out string
+	switch {
+	case headingLine.MatchString(line):
+		m := headingLine.FindStringSubmatch(line)
+		color := terminal.Bold + terminal.Magenta
+		if len(m[1]) > 2 {
+			color = terminal.Bold
+		}
+		out = color + inline(m[2], color) + terminal.Reset
+	case ruleLine.MatchString(line):
+		out = terminal.Dim + strings.Repeat("─", 40) + terminal.Reset
+	case listLine.MatchString(line):
+		m := listLine.FindStringSubmatch(line)
+		out = m[1] + terminal.Cyan + "•" + terminal.Reset + " " + inline(m[3], "")
+	case orderedLine.MatchString(line):
+		m := orderedLine.FindStringSubmatch(line)
+		out = m[1] + terminal.Cyan + m[2] + terminal.Reset + " " + inline(m[3], "")
+	case strings.HasPrefix(strings.TrimLeft(line, " "), ">"):
+		text := strings.TrimPrefix(strings.TrimLeft(line, " "), ">")
+		out = terminal.Dim + "│" + terminal.Reset + " " + terminal.Italic + inline(strings.TrimPrefix(text, " "), terminal.Italic) + terminal.Reset
+	default:
+		out = inline(line, "")
+	}
+	io.WriteString(r.w, out)
+}
+
+var inlinePattern = regexp.MustCompile("`[^`]+`|\\*\\*[^*]+\\*\\*|__[^_]+__|\\*[^*\\s][^*]*\\*|\\[[^\\]]+\\]\\([^)\\s]+\\)")
+
+// inline 渲染行内元素：行内代码、加粗、斜体、链接，outer 为外层样式，渲染后恢复
+func inline(text, outer string) string {
+	return inlinePattern.ReplaceAllStringFunc(text, func(s string) string {
+		restore := terminal.Reset + outer
+		switch {
+		case strings.HasPrefix(s, "`"):
+			return terminal.Cyan + strings.Trim(s, "`") + restore
+		case strings.HasPrefix(s, "**"), strings.HasPrefix(s, "__"):
+			return terminal.Bold + s[2:len(s)-2] + restore
+		case strings.HasPrefix(s, "*"):
+			return terminal.Italic + s[1:len(s)-1] + restore
+		default:
+			i := strings.Index(s, "](")
+			return s[1:i] + " " + terminal.Underline + terminal.Blue + s[i+2:len(s)-1] + restore
+		}
+	})
+}
@@ -5,121 +5,192 @@ import (
 	"fmt"
 	"github.com/sashabaranov/go-openai"
 	"io"
-	"strings"
 )
 
+func init() {
+	RegisterProvider(&compatibleProvider{
+		name:         "deepseek",
+		description:  "DeepSeek 官方接口",
+		baseURL:      "https://api.deepseek.com/v1",
+		fimBaseURL:   "https://api.deepseek.com/beta",
+		defaultModel: "deepseek-coder",
+		capabilities: Capabilities{
+			Stream:         true,
+			FIM:            true,
+			RequiresApiKey: true,
+			ContextWindow:  65536,
+		},
+		models: []ModelInfo{
+			{Name: "deepseek-coder", ContextWindow: 65536},
+			{Name: "deepseek-chat", ContextWindow: 65536},
+			{Name: "deepseek-reasoner", ContextWindow: 65536},
+		},
+		aliases: map[string]string{
+			"coder":    "deepseek-coder",
+			"chat":     "deepseek-chat",
+			"reasoner": "deepseek-reasoner",
+		},
+	})
+}
+
+// Client 兼容 OpenAI 接口协议的模型客户端
 type Client struct {
-	client *openai.Client
-	Model  string
+	client       *openai.Client
+	fimClient    *openai.Client // FIM 补全使用的客户端，接口地址可能与对话不同
+	Model        string
+	Temperature  *float32 // 设置后覆盖请求中的温度
+	capabilities Capabilities
 }
 
-func NewClient(authToken string) *Client {
+// NewCompatibleClient 创建兼容 OpenAI 接口协议的客户端
+func NewCompatibleClient(baseURL, authToken, model string, capabilities Capabilities) *Client {
 	config := openai.DefaultConfig(authToken)
-	config.BaseURL = "https://api.deepseek.com/v1"
+	config.BaseURL = baseURL
 	client := openai.NewClientWithConfig(config)
 	return &Client{
-		client: client,
-		Model:  "deepseek-coder",
+		client:       client,
+		fimClient:    client,
+		Model:        model,
+		capabilities: capabilities,
 	}
 }
 
-// StreamCodeCompletionWithPrompt 流式代码补全，实时输出补全过程
-func (c *Client) StreamCodeCompletionWithPrompt(prompt string, callback func(string)) error {
-	ctx := context.Background()
-	req := openai.ChatCompletionRequest{
-		Model: c.Model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "你是一个专业的代码补全助手。只返回代码，不要添加任何解释或markdown格式。",
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature: 0.1,
-		Stream:      true,
+// SetFIMBaseURL 设置 FIM 补全接口地址
+func (c *Client) SetFIMBaseURL(baseURL, authToken string) {
+	config := openai.DefaultConfig(authToken)
+	config.BaseURL = baseURL
+	c.fimClient = openai.NewClientWithConfig(config)
+}
+
+func (c *Client) ModelName() string {
+	return c.Model
+}
+
+func (c *Client) Capabilities() Capabilities {
+	return c.capabilities
+}
+
+func (c *Client) buildRequest(req ChatRequest, stream bool) openai.ChatCompletionRequest {
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    m.Role,
+			Content: m.Content,
+		})
+	}
+	temperature := req.Temperature
+	if c.Temperature != nil {
+		temperature = *c.Temperature
+	}
+	r := openai.ChatCompletionRequest{
+		Model:       c.Model,
+		Messages:    messages,
+		Temperature: temperature,
+		MaxTokens:   req.MaxTokens,
+		Stream:      stream,
+	}
+	if stream {
+		r.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
 	}
+	return r
+}
 
-	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+// Stream 流式对话
+func (c *Client) Stream(ctx context.Context, req ChatRequest, callback func(string)) (*ChatResponse, error) {
+	stream, err := c.client.CreateChatCompletionStream(ctx, c.buildRequest(req, true))
 	if err != nil {
-		return fmt.Errorf("创建流式代码补全失败: %v", err)
+		return nil, fmt.Errorf("创建流式对话失败: %v", err)
 	}
 	defer stream.Close()
 
+	result := &ChatResponse{}
+	var content []byte
 	for {
 		response, err := stream.Recv()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("接收流式代码补全数据失败: %v", err)
+			return nil, fmt.Errorf("接收流式数据失败: %v", err)
 		}
 
+		if response.Usage != nil {
+			result.Usage = toUsage(*response.Usage)
+		}
 		if len(response.Choices) > 0 {
 			delta := response.Choices[0].Delta
 			if delta.Content != "" {
+				content = append(content, delta.Content...)
 				callback(delta.Content)
 			}
 		}
 	}
-	return nil
+	result.Content = string(content)
+	return result, nil
 }
 
-// 简单清理，移除可能的markdown代码块标记
-func (c *Client) TrimMarkdown(code string) string {
-	completedCode := strings.TrimSpace(code)
-	if strings.HasPrefix(completedCode, "```") {
-		lines := strings.Split(completedCode, "\n")
-		if len(lines) > 2 {
-			// 移除第一行的```language和最后一行的```
-			completedCode = strings.Join(lines[1:len(lines)-1], "\n")
-		}
+// Complete 非流式对话
+func (c *Client) Complete(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	response, err := c.client.CreateChatCompletion(ctx, c.buildRequest(req, false))
+	if err != nil {
+		return nil, fmt.Errorf("对话失败: %v", err)
 	}
-	return completedCode
+	result := &ChatResponse{Usage: toUsage(response.Usage)}
+	if len(response.Choices) > 0 {
+		result.Content = response.Choices[0].Message.Content
+	}
+	return result, nil
 }
 
-// StreamCodeAskWithPrompt 流式代码咨询
-func (c *Client) StreamCodeAskWithPrompt(prompt string, callback func(string)) error {
-	ctx := context.Background()
-	req := openai.ChatCompletionRequest{
-		Model: c.Model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "你是一个专业的开发者，回答代码相关问题",
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature: 0.1,
-		Stream:      true,
+func toUsage(u openai.Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
 	}
+}
 
-	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+// StreamFIM 流式 fill-in-the-middle 补全
+func (c *Client) StreamFIM(ctx context.Context, req FIMRequest, callback func(string)) (*ChatResponse, error) {
+	if !c.capabilities.FIM {
+		return nil, fmt.Errorf("模型 %s 不支持 FIM 补全", c.Model)
+	}
+	temperature := req.Temperature
+	if c.Temperature != nil {
+		temperature = *c.Temperature
+	}
+	stream, err := c.fimClient.CreateCompletionStream(ctx, openai.CompletionRequest{
+		Model:         c.Model,
+		Prompt:        req.Prefix,
+		Suffix:        req.Suffix,
+		Temperature:   temperature,
+		MaxTokens:     req.MaxTokens,
+		Stream:        true,
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	})
 	if err != nil {
-		return fmt.Errorf("创建流式代码补全失败: %v", err)
+		return nil, fmt.Errorf("创建 FIM 补全失败: %v", err)
 	}
 	defer stream.Close()
 
+	result := &ChatResponse{}
+	var content []byte
 	for {
 		response, err := stream.Recv()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("接收流式数据失败: %v", err)
+			return nil, fmt.Errorf("接收 FIM 补全数据失败: %v", err)
 		}
-
-		if len(response.Choices) > 0 {
-			delta := response.Choices[0].Delta
-			if delta.Content != "" {
-				callback(delta.Content)
-			}
+		if response.Usage.TotalTokens > 0 {
+			result.Usage = toUsage(response.Usage)
+		}
+		if len(response.Choices) > 0 && response.Choices[0].Text != "" {
+			content = append(content, response.Choices[0].Text...)
+			callback(response.Choices[0].Text)
 		}
 	}
-	return nil
+	result.Content = string(content)
+	return result, nil
 }
@@ -0,0 +1,26 @@
+package openai
+
+func init() {
+	RegisterProvider(&compatibleProvider{
+		name:         "ollama",
+		description:  "本地 Ollama 服务，无需 api key",
+		baseURL:      "http://localhost:11434/v1",
+		baseURLEnv:   "OLLAMA_HOST",
+		defaultModel: "qwen2.5-coder:7b",
+		capabilities: Capabilities{
+			Stream:        true,
+			FIM:           true,
+			ContextWindow: 8192,
+		},
+	})
+	RegisterProvider(&compatibleProvider{
+		name:         "llamacpp",
+		description:  "本地 llama.cpp server，无需 api key",
+		baseURL:      "http://localhost:8080/v1",
+		defaultModel: "default",
+		capabilities: Capabilities{
+			Stream:        true,
+			ContextWindow: 8192,
+		},
+	})
+}
@@ -0,0 +1,247 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Message 对话消息
+type Message struct {
+	Role    string `json:"role"`    // 角色：system / user / assistant
+	Content string `json:"content"` // 消息内容
+}
+
+// 常用消息角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatRequest 对话请求
+type ChatRequest struct {
+	Messages    []Message // 消息列表
+	Temperature float32   // 采样温度
+	MaxTokens   int       // 最大输出 token 数，0 表示使用服务端默认值
+}
+
+// Usage token 用量
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+// ChatResponse 对话结果
+type ChatResponse struct {
+	Content string // 完整回答
+	Usage   Usage  // token 用量，服务端未返回时为零值
+}
+
+// Capabilities 模型能力描述
+type Capabilities struct {
+	Stream         bool // 是否支持流式输出
+	FIM            bool // 是否支持 fill-in-the-middle 补全
+	RequiresApiKey bool // 是否必须提供 api key
+	ContextWindow  int  // 上下文窗口大小（token 数）
+}
+
+// ModelInfo 模型信息
+type ModelInfo struct {
+	Name          string // 模型名称
+	ContextWindow int    // 上下文窗口大小（token 数）
+}
+
+// ChatModel 大模型对话接口，屏蔽不同厂商的差异
+type ChatModel interface {
+	// Stream 流式对话，每收到一段增量内容回调一次，结束后返回完整结果
+	Stream(ctx context.Context, req ChatRequest, callback func(string)) (*ChatResponse, error)
+	// Complete 非流式对话，返回完整结果
+	Complete(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+	// ModelName 获取模型名称
+	ModelName() string
+	// Capabilities 获取模型能力
+	Capabilities() Capabilities
+}
+
+// FIMRequest fill-in-the-middle 补全请求
+type FIMRequest struct {
+	Prefix      string  // 光标之前的内容
+	Suffix      string  // 光标之后的内容
+	Temperature float32 // 采样温度
+	MaxTokens   int     // 最大输出 token 数
+}
+
+// FIMModel 支持 fill-in-the-middle 补全的模型，使用前需检查 Capabilities().FIM
+type FIMModel interface {
+	// StreamFIM 流式补全光标处的内容
+	StreamFIM(ctx context.Context, req FIMRequest, callback func(string)) (*ChatResponse, error)
+}
+
+// Options 创建模型时的参数
+type Options struct {
+	ApiKey  string // api key
+	Model   string // 模型名称，为空时使用提供方默认模型
+	BaseURL string // 服务地址，为空时使用提供方默认地址
+
+	Temperature *float32 // 采样温度，设置后覆盖请求中的温度
+}
+
+// Provider 大模型提供方
+type Provider interface {
+	// Name 提供方名称，用于 --provider 参数
+	Name() string
+	// Description 提供方描述
+	Description() string
+	// DefaultModel 默认模型
+	DefaultModel() string
+	// Capabilities 默认模型的能力
+	Capabilities() Capabilities
+	// Models 已知的模型列表，为空表示不限制
+	Models() []ModelInfo
+	// New 根据参数创建模型
+	New(opts Options) (ChatModel, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+// RegisterProvider 注册提供方，同名提供方会被覆盖
+func RegisterProvider(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[strings.ToLower(p.Name())] = p
+}
+
+// GetProvider 根据名称获取提供方
+func GetProvider(name string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[strings.ToLower(name)]
+	return p, ok
+}
+
+// ProviderNames 获取所有已注册的提供方名称（已排序）
+func ProviderNames() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewChatModel 根据提供方名称创建模型
+func NewChatModel(name string, opts Options) (ChatModel, error) {
+	p, ok := GetProvider(name)
+	if !ok {
+		return nil, fmt.Errorf("未知的模型提供方 %q，可选值: %s", name, strings.Join(ProviderNames(), ", "))
+	}
+	return p.New(opts)
+}
+
+// compatibleProvider 兼容 OpenAI 接口协议的提供方
+type compatibleProvider struct {
+	name         string
+	description  string
+	baseURL      string
+	baseURLEnv   string // 覆盖默认服务地址的环境变量
+	fimBaseURL   string // FIM 补全接口地址，为空时使用 baseURL
+	defaultModel string
+	capabilities Capabilities
+	models       []ModelInfo       // 已知模型
+	aliases      map[string]string // 模型别名，如 "max" -> "qwen-max"
+}
+
+func (p *compatibleProvider) Name() string {
+	return p.name
+}
+
+func (p *compatibleProvider) Description() string {
+	return p.description
+}
+
+func (p *compatibleProvider) DefaultModel() string {
+	return p.defaultModel
+}
+
+func (p *compatibleProvider) Capabilities() Capabilities {
+	return p.capabilities
+}
+
+func (p *compatibleProvider) Models() []ModelInfo {
+	return p.models
+}
+
+// resolveModel 解析模型别名，返回模型名称和对应的能力
+func (p *compatibleProvider) resolveModel(model string) (string, Capabilities) {
+	capabilities := p.capabilities
+	if model == "" {
+		model = p.defaultModel
+	}
+	if name, ok := p.aliases[strings.ToLower(model)]; ok {
+		model = name
+	}
+	for _, m := range p.models {
+		if strings.EqualFold(m.Name, model) {
+			model = m.Name
+			if m.ContextWindow > 0 {
+				capabilities.ContextWindow = m.ContextWindow
+			}
+			break
+		}
+	}
+	return model, capabilities
+}
+
+func (p *compatibleProvider) New(opts Options) (ChatModel, error) {
+	if p.capabilities.RequiresApiKey && opts.ApiKey == "" {
+		return nil, fmt.Errorf("%s apiKey为空", p.name)
+	}
+	baseURL := opts.BaseURL
+	if baseURL == "" && p.baseURLEnv != "" {
+		baseURL = normalizeBaseURL(os.Getenv(p.baseURLEnv))
+	}
+	if baseURL == "" {
+		baseURL = p.baseURL
+	}
+	model, capabilities := p.resolveModel(opts.Model)
+	client := NewCompatibleClient(baseURL, opts.ApiKey, model, capabilities)
+	client.Temperature = opts.Temperature
+	if capabilities.FIM && p.fimBaseURL != "" && opts.BaseURL == "" {
+		client.SetFIMBaseURL(p.fimBaseURL, opts.ApiKey)
+	}
+	return client, nil
+}
+
+// ModelNames 获取提供方已知模型名称
+func ModelNames(p Provider) []string {
+	names := make([]string, 0, len(p.Models()))
+	for _, m := range p.Models() {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// normalizeBaseURL 补全服务地址的协议和 /v1 路径，例如 "127.0.0.1:11434" -> "http://127.0.0.1:11434/v1"
+func normalizeBaseURL(s string) string {
+	s = strings.TrimRight(strings.TrimSpace(s), "/")
+	if s == "" {
+		return ""
+	}
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	if !strings.HasSuffix(s, "/v1") {
+		s += "/v1"
+	}
+	return s
+}
@@ -1,15 +1,32 @@
 package openai
 
-import (
-	"github.com/sashabaranov/go-openai"
-)
-
-func NewQwenClient(authToken string) *Client {
-	config := openai.DefaultConfig(authToken)
-	config.BaseURL = "https://dashscope.aliyuncs.com/compatible-mode/v1"
-	client := openai.NewClientWithConfig(config)
-	return &Client{
-		client: client,
-		Model:  "qwen-plus",
-	}
+func init() {
+	RegisterProvider(&compatibleProvider{
+		name:         "qwen",
+		description:  "阿里云百炼通义千问兼容接口",
+		baseURL:      "https://dashscope.aliyuncs.com/compatible-mode/v1",
+		defaultModel: "qwen-plus",
+		capabilities: Capabilities{
+			Stream:         true,
+			RequiresApiKey: true,
+			ContextWindow:  131072,
+		},
+		models: []ModelInfo{
+			{Name: "qwen-plus", ContextWindow: 131072},
+			{Name: "qwen-max", ContextWindow: 32768},
+			{Name: "qwen-turbo", ContextWindow: 1000000},
+			{Name: "qwen-coder-plus", ContextWindow: 131072},
+			{Name: "qwen-coder-turbo", ContextWindow: 131072},
+			{Name: "qwen3-coder-plus", ContextWindow: 1000000},
+			{Name: "qwen2.5-coder-32b-instruct", ContextWindow: 131072},
+		},
+		aliases: map[string]string{
+			"plus":        "qwen-plus",
+			"max":         "qwen-max",
+			"turbo":       "qwen-turbo",
+			"coder":       "qwen-coder-plus",
+			"coder-plus":  "qwen-coder-plus",
+			"coder-turbo": "qwen-coder-turbo",
+		},
+	})
 }
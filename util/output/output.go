@@ -0,0 +1,298 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"github.com/MenciusCheng/go-cli/util/markdown"
+	"github.com/MenciusCheng/go-cli/util/openai"
+)
+
+// 输出模式
+const (
+	Text   = "text"   // 面向用户的文本输出
+	JSON   = "json"   // 结束后输出一个 json 对象
+	NDJSON = "ndjson" // 每行输出一个 json 事件
+)
+
+// Modes 支持的输出模式
+var Modes = []string{Text, JSON, NDJSON}
+
+// 事件类型
+const (
+	EventStrategy = "strategy" // 选中的策略
+	EventPrompt   = "prompt"   // 发送给大模型的提示词
+	EventToken    = "token"    // 流式回答的增量内容
+	EventAnswer   = "answer"   // 完整的回答
+	EventDiff     = "diff"     // 修改预览的统一格式差异
+	EventEdit     = "edit"     // 文件修改的范围
+	EventUsage    = "usage"    // token 用量
+	EventExplain  = "explain"  // 策略的匹配结果
+	EventInfo     = "info"     // 提示信息
+	EventError    = "error"    // 错误
+	EventDone     = "done"     // 结束
+)
+
+// Event ndjson 模式下输出的事件
+type Event struct {
+	Type       string        `json:"type"`
+	Strategy   string        `json:"strategy,omitempty"`
+	Content    string        `json:"content,omitempty"`
+	SessionID  string        `json:"sessionID,omitempty"`
+	Provider   string        `json:"provider,omitempty"`
+	Model      string        `json:"model,omitempty"`
+	Edit       *Edit         `json:"edit,omitempty"`
+	Candidates []Candidate   `json:"candidates,omitempty"`
+	Usage      *openai.Usage `json:"usage,omitempty"`
+	Error      *Error        `json:"error,omitempty"`
+}
+
+// Edit 文件修改的范围，行号从 1 开始，结束行包含在范围内
+// 纯插入时 EndLine 为 StartLine-1，纯删除时 NewEndLine 为 NewStartLine-1
+type Edit struct {
+	FilePath     string `json:"filePath"`
+	StartLine    int    `json:"startLine"`    // 原文件中被替换的开始行
+	EndLine      int    `json:"endLine"`      // 原文件中被替换的结束行
+	NewStartLine int    `json:"newStartLine"` // 修改后文件中的开始行
+	NewEndLine   int    `json:"newEndLine"`   // 修改后文件中的结束行
+	Text         string `json:"text"`         // 替换后的内容
+	Applied      bool   `json:"applied"`      // 是否已写入文件，dry-run 时为 false
+}
+
+// Candidate 策略的匹配结果，用于 --explain
+type Candidate struct {
+	Strategy string `json:"strategy"`
+	Score    int    `json:"score"`  // 匹配得分，0 表示不能处理
+	Reason   string `json:"reason"` // 匹配或不匹配的原因
+	Selected bool   `json:"selected"`
+}
+
+// Error 结构化输出中的错误
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Answer 一次完整的回答
+type Answer struct {
+	Content   string
+	SessionID string
+	Provider  string
+	Model     string
+	Usage     openai.Usage
+}
+
+// Result json 模式下结束时输出的对象
+type Result struct {
+	Strategy   string       `json:"strategy,omitempty"`
+	Prompt     string       `json:"prompt,omitempty"`
+	SessionID  string       `json:"sessionID,omitempty"`
+	Provider   string       `json:"provider,omitempty"`
+	Model      string       `json:"model,omitempty"`
+	Answer     string       `json:"answer"`
+	Diff       string       `json:"diff,omitempty"`
+	Edits      []Edit       `json:"edits,omitempty"`
+	Candidates []Candidate  `json:"candidates,omitempty"`
+	Usage      openai.Usage `json:"usage"`
+	Messages   []string     `json:"messages,omitempty"`
+	Error      *Error       `json:"error,omitempty"`
+}
+
+// Writer 按输出模式输出策略执行过程
+type Writer struct {
+	mode     string
+	w        io.Writer
+	renderer *markdown.Renderer // 文本模式下渲染 markdown 格式的回答，为空时原样输出
+	result   Result
+	answer   strings.Builder
+}
+
+// New 创建输出，renderMarkdown 只在文本模式下生效
+func New(mode string, w io.Writer, renderMarkdown bool) (*Writer, error) {
+	switch mode {
+	case "":
+		mode = Text
+	case Text, JSON, NDJSON:
+	default:
+		return nil, errcode.Wrap(errcode.InvalidArgument,
+			fmt.Errorf("不支持的输出模式 %q，可选值: %s", mode, strings.Join(Modes, ", ")))
+	}
+	out := &Writer{mode: mode, w: w}
+	if mode == Text && renderMarkdown {
+		out.renderer = markdown.NewRenderer(w)
+	}
+	return out, nil
+}
+
+// Mode 输出模式
+func (o *Writer) Mode() string {
+	return o.mode
+}
+
+// Structured 是否为结构化输出（json 或 ndjson），结构化输出时不能与用户交互
+func (o *Writer) Structured() bool {
+	return o.mode != Text
+}
+
+// Infof 输出提示信息，文本模式下输出到日志（标准错误），标准输出只保留回答和结果
+func (o *Writer) Infof(format string, args ...interface{}) {
+	switch o.mode {
+	case Text:
+		logger.Infof(format, args...)
+	default:
+		msg := strings.TrimSpace(fmt.Sprintf(format, args...))
+		if msg == "" {
+			return
+		}
+		o.result.Messages = append(o.result.Messages, msg)
+		o.emit(Event{Type: EventInfo, Content: msg})
+	}
+}
+
+// Strategy 记录选中的策略，文本模式下由策略自行输出
+func (o *Writer) Strategy(name string) {
+	o.result.Strategy = name
+	o.emit(Event{Type: EventStrategy, Strategy: name})
+}
+
+// Prompt 输出发送给大模型的提示词
+// 文本模式下提示词可能包含整个文件，只在 debug 级别输出到日志；结构化输出时始终输出，便于编辑器插件展示
+func (o *Writer) Prompt(prompt string) {
+	if o.mode == Text {
+		logger.Debugf("\n=== 提示词 ===\n%s\n", prompt)
+		return
+	}
+	o.result.Prompt = prompt
+	o.emit(Event{Type: EventPrompt, Content: prompt})
+}
+
+// Token 输出流式回答的增量内容
+func (o *Writer) Token(token string) {
+	switch {
+	case o.mode == NDJSON:
+		o.emit(Event{Type: EventToken, Content: token})
+	case o.mode == JSON:
+		o.answer.WriteString(token)
+	case o.renderer != nil:
+		o.renderer.WriteString(token)
+	default:
+		fmt.Fprint(o.w, token)
+	}
+}
+
+// Answer 输出完整的回答，多次调用时（如修复代码）json 模式下保留最后一次的回答，用量累加
+func (o *Writer) Answer(a Answer) {
+	if o.renderer != nil {
+		o.renderer.Flush()
+	}
+	if o.mode == Text {
+		// 回答以换行结束，避免与之后输出到标准错误的提示信息连在一起
+		if a.Content != "" && !strings.HasSuffix(a.Content, "\n") {
+			fmt.Fprintln(o.w)
+		}
+		return
+	}
+	o.answer.Reset()
+	o.result.Answer = a.Content
+	o.result.SessionID = a.SessionID
+	o.result.Provider = a.Provider
+	o.result.Model = a.Model
+	o.result.Usage.PromptTokens += a.Usage.PromptTokens
+	o.result.Usage.CompletionTokens += a.Usage.CompletionTokens
+	o.result.Usage.TotalTokens += a.Usage.TotalTokens
+	o.emit(Event{Type: EventAnswer, Content: a.Content, SessionID: a.SessionID, Provider: a.Provider, Model: a.Model})
+	usage := a.Usage
+	o.emit(Event{Type: EventUsage, Usage: &usage})
+}
+
+// Raw 输出不经过 markdown 渲染的结果，如 JSON 格式的调试信息
+// 文本模式下原样输出到标准输出，结构化输出时作为完整的回答
+func (o *Writer) Raw(content string) {
+	if o.mode == Text {
+		if o.renderer != nil {
+			o.renderer.Flush()
+		}
+		fmt.Fprint(o.w, content)
+		if !strings.HasSuffix(content, "\n") {
+			fmt.Fprintln(o.w)
+		}
+		return
+	}
+	o.answer.Reset()
+	o.result.Answer = content
+	o.emit(Event{Type: EventAnswer, Content: content})
+}
+
+// Diff 输出修改预览的统一格式差异，文本模式下由调用方负责着色输出
+func (o *Writer) Diff(unified string) {
+	o.result.Diff = unified
+	o.emit(Event{Type: EventDiff, Content: unified})
+}
+
+// Explain 输出每个策略的匹配结果
+func (o *Writer) Explain(candidates []Candidate) {
+	if o.mode == Text {
+		for _, c := range candidates {
+			mark := " "
+			if c.Selected {
+				mark = "*"
+			}
+			fmt.Fprintf(o.w, "%s %-20s %4d  %s\n", mark, c.Strategy, c.Score, c.Reason)
+		}
+		return
+	}
+	o.result.Candidates = candidates
+	o.emit(Event{Type: EventExplain, Candidates: candidates})
+}
+
+// Edit 输出文件修改的范围
+func (o *Writer) Edit(edit Edit) {
+	o.result.Edits = append(o.result.Edits, edit)
+	o.emit(Event{Type: EventEdit, Edit: &edit})
+}
+
+// Close 结束输出，json 模式下输出最终结果，ndjson 模式下输出错误和结束事件
+// 返回传入的错误，便于命令以非零状态退出
+func (o *Writer) Close(err error) error {
+	if o.renderer != nil {
+		o.renderer.Flush()
+	}
+	if o.mode == Text {
+		return err
+	}
+	if err != nil {
+		o.result.Error = &Error{Code: errcode.Of(err), Message: err.Error()}
+		o.emit(Event{Type: EventError, Error: o.result.Error})
+	}
+	switch o.mode {
+	case JSON:
+		if o.result.Answer == "" {
+			// 回答中断时输出已收到的部分
+			o.result.Answer = o.answer.String()
+		}
+		enc := json.NewEncoder(o.w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if encodeErr := enc.Encode(o.result); encodeErr != nil {
+			return fmt.Errorf("序列化输出失败: %w", encodeErr)
+		}
+	case NDJSON:
+		o.emit(Event{Type: EventDone})
+	}
+	return err
+}
+
+// emit ndjson 模式下输出一行事件
+func (o *Writer) emit(ev Event) {
+	if o.mode != NDJSON {
+		return
+	}
+	// 回答中常包含 <、> 等字符，不做 HTML 转义，便于阅读
+	enc := json.NewEncoder(o.w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(ev)
+}
@@ -49,6 +49,13 @@ func (tr *Renderer) registerDefaultFuncs() {
 	tr.funcMap["contains"] = strings.Contains
 	tr.funcMap["hasPrefix"] = strings.HasPrefix
 	tr.funcMap["hasSuffix"] = strings.HasSuffix
+	tr.funcMap["trimPrefix"] = strings.TrimPrefix
+	tr.funcMap["trimSuffix"] = strings.TrimSuffix
+
+	// 路径处理函数
+	tr.funcMap["base"] = filepath.Base
+	tr.funcMap["dir"] = filepath.Dir
+	tr.funcMap["ext"] = filepath.Ext
 
 	// 格式化函数
 	tr.funcMap["sprintf"] = fmt.Sprintf
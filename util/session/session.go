@@ -0,0 +1,327 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/budget"
+	"github.com/MenciusCheng/go-cli/util/config"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Turn 一轮问答
+type Turn struct {
+	Time     time.Time    `json:"time"`               // 回答完成时间
+	Prompt   string       `json:"prompt"`             // 用户输入的问题
+	Rendered string       `json:"rendered,omitempty"` // 实际发送给大模型的内容（渲染后的模板或附带的文件），与问题相同时为空
+	FilePath string       `json:"filePath,omitempty"` // 相关的文件路径
+	Answer   string       `json:"answer"`             // 大模型的回答
+	Provider string       `json:"provider,omitempty"` // 大模型提供方
+	BaseURL  string       `json:"baseURL,omitempty"`  // 自定义服务地址，为空时使用提供方的默认地址
+	Model    string       `json:"model"`              // 使用的模型
+	Usage    openai.Usage `json:"usage"`              // token 用量
+}
+
+// Content 实际发送给大模型的用户消息
+func (t *Turn) Content() string {
+	if t.Rendered != "" {
+		return t.Rendered
+	}
+	return t.Prompt
+}
+
+// Session 一次会话，保存全部问答
+type Session struct {
+	ID    string `json:"id"`
+	Turns []Turn `json:"turns"`
+}
+
+// Messages 会话对应的对话消息，system 不为空时作为系统提示词
+func (s *Session) Messages(system string) []openai.Message {
+	return messages(system, s.Turns)
+}
+
+// MessagesWithin 同 Messages，历史问答超出 maxTokens 时从最早的一轮开始省略，返回省略的轮数
+// 系统提示词不计入 maxTokens
+func (s *Session) MessagesWithin(system string, maxTokens int) ([]openai.Message, int) {
+	first, used := len(s.Turns), 0
+	for first > 0 {
+		t := s.Turns[first-1]
+		used += budget.DefaultTokenizer.Count(t.Content()) + budget.DefaultTokenizer.Count(t.Answer)
+		if used > maxTokens {
+			break
+		}
+		first--
+	}
+	return messages(system, s.Turns[first:]), first
+}
+
+func messages(system string, turns []Turn) []openai.Message {
+	var messages []openai.Message
+	if system != "" {
+		messages = append(messages, openai.Message{Role: openai.RoleSystem, Content: system})
+	}
+	for _, t := range turns {
+		messages = append(messages,
+			openai.Message{Role: openai.RoleUser, Content: t.Content()},
+			openai.Message{Role: openai.RoleAssistant, Content: t.Answer},
+		)
+	}
+	return messages
+}
+
+// Title 会话标题，取第一个问题
+func (s *Session) Title() string {
+	if len(s.Turns) == 0 {
+		return ""
+	}
+	return s.Turns[0].Prompt
+}
+
+// FilePath 会话最近一次相关的文件路径
+func (s *Session) FilePath() string {
+	for i := len(s.Turns) - 1; i >= 0; i-- {
+		if s.Turns[i].FilePath != "" {
+			return s.Turns[i].FilePath
+		}
+	}
+	return ""
+}
+
+// Updated 会话最后更新时间
+func (s *Session) Updated() time.Time {
+	if len(s.Turns) == 0 {
+		return time.Time{}
+	}
+	return s.Turns[len(s.Turns)-1].Time
+}
+
+// Usage 会话累计的 token 用量
+func (s *Session) Usage() openai.Usage {
+	var u openai.Usage
+	for _, t := range s.Turns {
+		u.PromptTokens += t.Usage.PromptTokens
+		u.CompletionTokens += t.Usage.CompletionTokens
+		u.TotalTokens += t.Usage.TotalTokens
+	}
+	return u
+}
+
+// Markdown 将会话导出为 markdown 文本
+func (s *Session) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 会话 %s\n\n", s.ID)
+	for _, t := range s.Turns {
+		fmt.Fprintf(&b, "## 用户\n\n%s\n\n", strings.TrimSpace(t.Prompt))
+		if t.FilePath != "" {
+			fmt.Fprintf(&b, "文件: `%s`\n\n", t.FilePath)
+		}
+		fmt.Fprintf(&b, "## 助手（%s，%s）\n\n%s\n\n", t.Model, t.Time.Format("2006-01-02 15:04:05"), strings.TrimSpace(t.Answer))
+	}
+	return b.String()
+}
+
+// Store 会话存储，每个会话保存为一个 JSONL 文件，每行一轮问答
+type Store struct {
+	dir string
+}
+
+// New 创建会话存储，保存在用户状态目录下
+func New() *Store {
+	return &Store{dir: filepath.Join(config.StateDir(), "sessions")}
+}
+
+// Dir 会话存储目录
+func (st *Store) Dir() string {
+	return st.dir
+}
+
+// NewID 生成新的会话编号，格式为 时间-随机数
+func (st *Store) NewID() string {
+	b := make([]byte, 2)
+	_, _ = rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+func (st *Store) path(id string) string {
+	return filepath.Join(st.dir, id+".jsonl")
+}
+
+// Append 追加一轮问答到会话，会话不存在时自动创建
+func (st *Store) Append(id string, t Turn) error {
+	if err := os.MkdirAll(st.dir, 0700); err != nil {
+		return fmt.Errorf("创建会话目录失败: %w", err)
+	}
+	if t.Time.IsZero() {
+		t.Time = time.Now()
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("序列化会话失败: %w", err)
+	}
+	f, err := os.OpenFile(st.path(id), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("写入会话失败: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入会话失败: %w", err)
+	}
+	return nil
+}
+
+// Last 表示最近更新的会话
+const Last = "last"
+
+// Resolve 根据编号或唯一的编号前缀查找会话编号，id 为 last 时返回最近更新的会话
+func (st *Store) Resolve(id string) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("会话编号不能为空")
+	}
+	if id == Last {
+		sessions, err := st.List()
+		if err != nil {
+			return "", err
+		}
+		if len(sessions) == 0 {
+			return "", fmt.Errorf("暂无会话记录")
+		}
+		return sessions[0].ID, nil
+	}
+	ids, err := st.ids()
+	if err != nil {
+		return "", err
+	}
+	var matched []string
+	for _, candidate := range ids {
+		if candidate == id {
+			return id, nil
+		}
+		if strings.HasPrefix(candidate, id) {
+			matched = append(matched, candidate)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return "", fmt.Errorf("未找到会话 %s", id)
+	case 1:
+		return matched[0], nil
+	default:
+		return "", fmt.Errorf("会话编号 %s 不唯一，匹配到: %s", id, strings.Join(matched, ", "))
+	}
+}
+
+// Load 读取会话，id 可以是唯一的编号前缀或 last
+func (st *Store) Load(id string) (*Session, error) {
+	id, err := st.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return st.load(id)
+}
+
+func (st *Store) load(id string) (*Session, error) {
+	data, err := os.ReadFile(st.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("读取会话失败: %w", err)
+	}
+	s := &Session{ID: id}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var t Turn
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return nil, fmt.Errorf("解析会话 %s 第 %d 行失败: %w", id, line, err)
+		}
+		s.Turns = append(s.Turns, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取会话失败: %w", err)
+	}
+	return s, nil
+}
+
+// ids 所有会话编号
+func (st *Store) ids() ([]string, error) {
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取会话目录失败: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, ".jsonl") {
+			ids = append(ids, strings.TrimSuffix(name, ".jsonl"))
+		}
+	}
+	return ids, nil
+}
+
+// List 获取所有会话，按最后更新时间倒序排列
+func (st *Store) List() ([]*Session, error) {
+	ids, err := st.ids()
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*Session, 0, len(ids))
+	for _, id := range ids {
+		s, err := st.load(id)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Updated().After(sessions[j].Updated())
+	})
+	return sessions, nil
+}
+
+// Delete 删除会话，id 可以是唯一的编号前缀或 last，返回实际删除的会话编号
+func (st *Store) Delete(id string) (string, error) {
+	id, err := st.Resolve(id)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(st.path(id)); err != nil {
+		return "", fmt.Errorf("删除会话失败: %w", err)
+	}
+	return id, nil
+}
+
+// Match 搜索命中的一轮问答
+type Match struct {
+	Session *Session
+	Turn    int // 命中的问答下标
+}
+
+// Search 在问题和回答中搜索关键字（不区分大小写），按会话更新时间倒序返回
+func (st *Store) Search(keyword string) ([]Match, error) {
+	sessions, err := st.List()
+	if err != nil {
+		return nil, err
+	}
+	keyword = strings.ToLower(keyword)
+	var matches []Match
+	for _, s := range sessions {
+		for i, t := range s.Turns {
+			if strings.Contains(strings.ToLower(t.Prompt), keyword) || strings.Contains(strings.ToLower(t.Answer), keyword) {
+				matches = append(matches, Match{Session: s, Turn: i})
+			}
+		}
+	}
+	return matches, nil
+}
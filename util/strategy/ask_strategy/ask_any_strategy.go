@@ -2,9 +2,8 @@ package ask_strategy
 
 import (
 	"fmt"
-	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/rule"
 	"github.com/MenciusCheng/go-cli/util/strategy"
-	"os"
 )
 
 func NewAskAnyStrategy() strategy.Strategy {
@@ -14,33 +13,32 @@ func NewAskAnyStrategy() strategy.Strategy {
 type AskAnyStrategy struct {
 }
 
-func (s *AskAnyStrategy) CanHandle(e *strategy.Event) bool {
-	return true
+func (s *AskAnyStrategy) CanHandle(e *strategy.Event) strategy.Match {
+	return strategy.Accept(strategy.ScoreDefault, "可以回答任意问题")
 }
 
 func (s *AskAnyStrategy) Handle(e *strategy.Event) error {
-	if e.DeepseekApiKey == "" {
-		return fmt.Errorf("apiKey为空")
+	tmpl, err := e.LoadTemplate(rule.ChatRule)
+	if err != nil {
+		return err
 	}
-
-	// 检查API密钥
-	apiKey := os.Getenv("DEEPSEEK_API_KEY")
-	if apiKey == "" {
-		return fmt.Errorf("未设置 DEEPSEEK_API_KEY 环境变量")
+	client, err := e.NewChatModel()
+	if err != nil {
+		return err
 	}
-	fmt.Printf("正在咨询大模型...\n\n")
-	client := openai.NewClient(e.DeepseekApiKey)
-	err := client.StreamCodeAskWithPrompt(e.Prompt, func(token string) {
-		// 流式打印内容
-		fmt.Print(token)
-	})
+	e.Out().Prompt(e.Prompt)
+	e.Out().Infof("正在咨询大模型...\n\n")
+	err = askWithSession(e, client, tmpl, "")
 	if err != nil {
-		return fmt.Errorf("咨询大模型失败: %v", err)
+		return fmt.Errorf("咨询大模型失败: %w", err)
 	}
-	fmt.Println() // 在回答结束后添加换行
 	return nil
 }
 
 func (s *AskAnyStrategy) GetName() string {
 	return "AskAnyStrategy"
 }
+
+func (s *AskAnyStrategy) Description() string {
+	return "直接向大模型提问，支持通过 --session 继续会话"
+}
@@ -1,9 +1,7 @@
 package ask_strategy
 
 import (
-	"fmt"
 	"github.com/MenciusCheng/go-cli/rule"
-	"github.com/MenciusCheng/go-cli/util/openai"
 	"github.com/MenciusCheng/go-cli/util/renderer"
 	"github.com/MenciusCheng/go-cli/util/strategy"
 )
@@ -15,53 +13,46 @@ func NewAskCodeStrategy() strategy.Strategy {
 type AskCodeStrategy struct {
 }
 
-func (s *AskCodeStrategy) CanHandle(e *strategy.Event) bool {
+func (s *AskCodeStrategy) CanHandle(e *strategy.Event) strategy.Match {
 	// 必须选中代码
 	if e.SelectedText == "" {
-		return false
+		return strategy.Reject("未选中代码")
 	}
-	return true
+	return strategy.Accept(strategy.ScoreSpecific, "选中了代码")
 }
 
 func (s *AskCodeStrategy) Handle(e *strategy.Event) error {
-	fmt.Printf("策略名称: %s\n", s.GetName())
-	fmt.Printf("任意代码咨询\n")
+	out := e.Out()
+	out.Infof("策略名称: %s\n", s.GetName())
+	out.Infof("任意代码咨询\n")
 
-	if e.DeepseekApiKey == "" {
-		return fmt.Errorf("apiKey为空")
+	tmpl, err := e.LoadTemplate(rule.AskRule)
+	if err != nil {
+		return err
 	}
 
-	eMap := e.ToMapByJSON()
-	if len([]rune(e.FileText)) > 100000 {
-		// 截取字符串
-		eMap["fileText"] = string([]rune(e.FileText)[:100000])
-	} else {
-		eMap["fileText"] = e.FileText
+	client, err := e.NewChatModel()
+	if err != nil {
+		return err
 	}
 
 	render := renderer.New()
-	tmplContent := rule.AskRuleTemplate
-	// 渲染模板
-	prompt, err := render.RenderString(tmplContent, eMap)
+	// 渲染模板，文件内容按模型的上下文窗口裁剪
+	prompt, err := e.RenderPrompt(render, tmpl.Body, e.ToMapByJSON(), client, tmpl)
 	if err != nil {
-		return fmt.Errorf("渲染模板失败: %w", err)
+		return err
 	}
 
-	fmt.Println("\n=== 提示词 ===")
-	fmt.Printf("%s\n", prompt)
+	out.Prompt(prompt)
 
-	fmt.Println("\n=== 大模型回答 ===")
-	client := openai.NewClient(e.DeepseekApiKey)
-	err = client.StreamCodeAskWithPrompt(prompt, func(token string) {
-		// 流式打印内容
-		fmt.Print(token)
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+	out.Infof("\n=== 大模型回答 ===\n")
+	return askWithSession(e, client, tmpl, prompt)
 }
 
 func (s *AskCodeStrategy) GetName() string {
 	return "AskCodeStrategy"
 }
+
+func (s *AskCodeStrategy) Description() string {
+	return "结合选中的代码和文件内容向大模型提问"
+}
@@ -0,0 +1,98 @@
+package ask_strategy
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/chat"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/util/session"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"os"
+)
+
+func NewChatStrategy() strategy.Strategy {
+	return &ChatStrategy{}
+}
+
+// ChatStrategy 终端多轮交互式对话
+type ChatStrategy struct {
+}
+
+func (s *ChatStrategy) CanHandle(e *strategy.Event) strategy.Match {
+	if !e.Interactive {
+		return strategy.Reject("未指定 -i 交互式对话")
+	}
+	return strategy.Accept(strategy.ScoreExplicit, "指定了 -i 交互式对话")
+}
+
+func (s *ChatStrategy) Handle(e *strategy.Event) error {
+	format, err := e.OutputFormat()
+	if err != nil {
+		return err
+	}
+	if format == strategy.FormatJSON || e.Out().Structured() {
+		return errcode.Wrap(errcode.InvalidArgument, fmt.Errorf("交互式对话不支持 json 输出"))
+	}
+	tmpl, err := e.LoadTemplate(rule.ChatRule)
+	if err != nil {
+		return err
+	}
+	client, err := e.NewChatModel()
+	if err != nil {
+		return err
+	}
+
+	store := session.New()
+	repl := chat.NewREPL(os.Stdin, os.Stdout, client, tmpl, store)
+	if e.SessionID != "" {
+		if repl.Session, err = store.Load(e.SessionID); err != nil {
+			return err
+		}
+	}
+	repl.Provider = e.ResolveProvider()
+	repl.BaseURL = e.BaseURL
+	repl.Markdown = format == strategy.FormatMarkdown
+	repl.FilePath = e.FilePath
+	if repl.FilePath == "" {
+		repl.FilePath = repl.Session.FilePath()
+	}
+	repl.MaxContextSize = e.MaxContextSize
+	current := *e
+	repl.NewModel = func(provider, model string) (openai.ChatModel, error) {
+		next := current
+		if provider != "" && provider != current.ResolveProvider() {
+			// 切换提供方时不再沿用原提供方的服务地址
+			next.Provider = provider
+			next.BaseURL = ""
+		}
+		next.Model = model
+		m, err := next.NewChatModel()
+		if err != nil {
+			return nil, err
+		}
+		current = next
+		repl.Provider = next.ResolveProvider()
+		repl.BaseURL = next.BaseURL
+		return m, nil
+	}
+
+	// 启动时选中的代码或文件作为第一条消息的附件
+	switch {
+	case e.SelectedText != "":
+		repl.AttachText(fmt.Sprintf("文件 `%s` 中选中的代码", e.FilePath), e.SelectedText)
+	case e.FilePath != "":
+		if err := repl.AttachFile(e.FilePath); err != nil {
+			return err
+		}
+	}
+	return repl.Run(e.Prompt)
+}
+
+func (s *ChatStrategy) GetName() string {
+	return "ChatStrategy"
+}
+
+func (s *ChatStrategy) Description() string {
+	return "多轮交互式对话，同 go-cli chat"
+}
@@ -0,0 +1,74 @@
+package ask_strategy
+
+import (
+	"context"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/util/output"
+	"github.com/MenciusCheng/go-cli/util/session"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"time"
+)
+
+// loadSession 读取事件指定的会话，未指定时创建新会话
+func loadSession(store *session.Store, e *strategy.Event) (*session.Session, error) {
+	if e.SessionID == "" {
+		return &session.Session{ID: store.NewID()}, nil
+	}
+	return store.Load(e.SessionID)
+}
+
+// askWithSession 在会话历史的基础上流式咨询，回答完成后保存到会话中
+// 系统提示词、温度等参数取自模板 tmpl 的 front matter，rendered 为实际发送的内容，为空时发送 e.Prompt
+func askWithSession(e *strategy.Event, client openai.ChatModel, tmpl *rule.Template, rendered string) error {
+	out := e.Out()
+	store := session.New()
+	s, err := loadSession(store, e)
+	if err != nil {
+		return err
+	}
+
+	content := e.Prompt
+	if rendered != "" {
+		content = rendered
+	}
+	// 历史问答超出上下文预算时省略最早的几轮
+	messages, dropped := s.MessagesWithin(tmpl.System, e.ContextTokens(client, tmpl, content))
+	if dropped > 0 {
+		out.Infof("会话历史超出上下文预算，省略了最早的 %d 轮问答\n", dropped)
+	}
+	messages = append(messages, openai.Message{Role: openai.RoleUser, Content: content})
+	resp, err := client.Stream(context.Background(), tmpl.Request(messages), out.Token)
+	if err != nil {
+		return errcode.Wrap(errcode.Provider, err)
+	}
+
+	turn := session.Turn{
+		Time:     time.Now(),
+		Prompt:   e.Prompt,
+		FilePath: e.FilePath,
+		Answer:   resp.Content,
+		Provider: e.ResolveProvider(),
+		BaseURL:  e.BaseURL,
+		Model:    client.ModelName(),
+		Usage:    resp.Usage,
+	}
+	if content != e.Prompt {
+		turn.Rendered = content
+	}
+	out.Answer(output.Answer{
+		Content:   turn.Answer,
+		SessionID: s.ID,
+		Provider:  turn.Provider,
+		Model:     turn.Model,
+		Usage:     turn.Usage,
+	})
+	if err := store.Append(s.ID, turn); err != nil {
+		logger.Warnf("保存会话失败: %v\n", err)
+		return nil
+	}
+	out.Infof("\n会话编号: %s（可使用 go-cli sessions resume %s 继续对话）\n", s.ID, s.ID)
+	return nil
+}
@@ -0,0 +1,55 @@
+package strategy
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/budget"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/util/renderer"
+)
+
+// ContextTokens 文件内容可以使用的 token 数：上下文窗口中可以用于输入的 token 减去系统提示词和不含文件内容的提示词 base，见 budget.InputTokens
+func (e *Event) ContextTokens(client openai.ChatModel, tmpl *rule.Template, base string) int {
+	input := budget.InputTokens(client.Capabilities().ContextWindow, tmpl.MaxTokens)
+	overhead := budget.DefaultTokenizer.Count(base) + budget.DefaultTokenizer.Count(tmpl.System)
+	logger.Debugf("上下文预算: 可用于输入 %d，提示词 %d tokens\n", input, overhead)
+	return input - overhead
+}
+
+// RenderPrompt 渲染提示词模板 body，data 中的 fileText 按模型的上下文窗口裁剪后再渲染
+//   - 预算见 ContextTokens
+//   - 超出预算时保留选中的代码及前后几行、导入、所在的声明和离选中区域最近的内容，省略的行通过 Infof 输出
+//   - 设置了 MaxContextSize 时文件内容同时不超过该字符数
+func (e *Event) RenderPrompt(render *renderer.Renderer, body string, data map[string]interface{}, client openai.ChatModel, tmpl *rule.Template) (string, error) {
+	data["fileText"] = ""
+	base, err := render.RenderString(body, data)
+	if err != nil {
+		return "", errcode.Wrap(errcode.Template, fmt.Errorf("渲染模板失败: %w", err))
+	}
+	if e.FileText == "" {
+		return base, nil
+	}
+
+	maxTokens := e.ContextTokens(client, tmpl, base)
+	result := budget.Fit(budget.Request{
+		Text:      e.FileText,
+		Language:  e.Language,
+		StartLine: e.SelectionStartLine,
+		EndLine:   e.SelectionEndLine,
+		MaxTokens: maxTokens,
+		MaxRunes:  e.MaxContextSize,
+	})
+	logger.Debugf("文件内容: %d/%d tokens\n", result.Tokens, maxTokens)
+	if summary := result.Summary(); summary != "" {
+		e.Out().Infof("%s\n", summary)
+	}
+
+	data["fileText"] = result.Text
+	prompt, err := render.RenderString(body, data)
+	if err != nil {
+		return "", errcode.Wrap(errcode.Template, fmt.Errorf("渲染模板失败: %w", err))
+	}
+	return prompt, nil
+}
@@ -1,12 +1,15 @@
 package code_strategy
 
 import (
+	"context"
 	"fmt"
 	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/extract"
 	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/util/output"
 	"github.com/MenciusCheng/go-cli/util/renderer"
 	"github.com/MenciusCheng/go-cli/util/strategy"
-	"os"
 	"strings"
 )
 
@@ -17,100 +20,149 @@ func NewCodeStrategy() strategy.Strategy {
 type CodeStrategy struct {
 }
 
-func (s *CodeStrategy) CanHandle(e *strategy.Event) bool {
+func (s *CodeStrategy) CanHandle(e *strategy.Event) strategy.Match {
 	// 必须选中代码
 	if e.SelectedText == "" {
-		return false
+		return strategy.Reject("未选中代码")
 	}
-	return true
+	return strategy.Accept(strategy.ScoreSpecific, "选中了代码")
 }
 
 func (s *CodeStrategy) Handle(e *strategy.Event) error {
-	fmt.Printf("策略名称: %s\n", s.GetName())
-	fmt.Printf("任意代码补全\n")
+	out := e.Out()
+	out.Infof("策略名称: %s\n", s.GetName())
+	out.Infof("任意代码补全\n")
 
-	if e.DeepseekApiKey == "" {
-		return fmt.Errorf("apiKey为空")
+	format := e.ResponseFormat
+	if format == "" {
+		format = rule.FormatReplace
+	}
+	if err := rule.CheckResponseFormat(format); err != nil {
+		return errcode.Wrap(errcode.InvalidArgument, err)
+	}
+	tmpl, err := e.LoadTemplate(rule.CodeRuleTemplates[format])
+	if err != nil {
+		return err
+	}
+	// 模板 front matter 中声明的返回格式决定如何解析回答
+	if tmpl.ResponseFormat != "" {
+		format = tmpl.ResponseFormat
+	}
+	repairTmpl, err := e.LoadTemplate(rule.RepairRule)
+	if err != nil {
+		return err
 	}
 
-	eMap := e.ToMapByJSON()
-	if len([]rune(e.FileText)) > 100000 {
-		// 截取字符串
-		eMap["fileText"] = string([]rune(e.FileText)[:100000])
-	} else {
-		eMap["fileText"] = e.FileText
+	client, err := e.NewChatModel()
+	if err != nil {
+		return err
 	}
 
 	render := renderer.New()
-	tmplContent := rule.CodeRuleTemplate
-	// 渲染模板
-	prompt, err := render.RenderString(tmplContent, eMap)
+	// 渲染模板，文件内容按模型的上下文窗口裁剪
+	prompt, err := e.RenderPrompt(render, tmpl.Body, e.ToMapByJSON(), client, tmpl)
 	if err != nil {
-		return fmt.Errorf("渲染模板失败: %w", err)
+		return err
 	}
 
-	fmt.Println("\n=== 提示词 ===")
-	fmt.Printf("%s\n", prompt)
+	out.Prompt(prompt)
 
-	fmt.Println("\n=== 正在执行代码补全 ===")
-	client := openai.NewClient(e.DeepseekApiKey)
-	var completedCode strings.Builder
-	err = client.StreamCodeCompletionWithPrompt(prompt, func(token string) {
-		// 流式打印内容
-		fmt.Print(token)
-		// 同时将内容写入到 builder 中
-		completedCode.WriteString(token)
-	})
+	out.Infof("\n=== 正在执行代码补全 ===\n")
+	messages := tmpl.Messages(prompt)
+	resp, err := streamCompletion(e, client, tmpl.Request(messages))
+	if err != nil {
+		return err
+	}
+
+	start, end, err := e.SelectionRange()
+	if err != nil {
+		return err
+	}
+	newContent, err := applyAnswer(e, format, resp.Content, start, end)
 	if err != nil {
 		return err
 	}
-	completedCodeStr := client.TrimMarkdown(completedCode.String())
 
-	fmt.Println("\n=== 正在替换代码 ===")
-	err = replaceCodeInFile(e.FilePath, e.FileText, e.SelectionStartLine, e.SelectionEndLine, completedCodeStr)
+	newContent, err = ValidateWithRepair(e, newContent, func(problems string) (string, error) {
+		repairPrompt, err := render.RenderString(repairTmpl.Body, map[string]interface{}{"problems": problems})
+		if err != nil {
+			return "", errcode.Wrap(errcode.Template, fmt.Errorf("渲染模板失败: %w", err))
+		}
+		messages = append(messages,
+			openai.Message{Role: openai.RoleAssistant, Content: resp.Content},
+			openai.Message{Role: openai.RoleUser, Content: repairPrompt},
+		)
+		resp, err = streamCompletion(e, client, tmpl.Request(messages))
+		if err != nil {
+			return "", err
+		}
+		return applyAnswer(e, format, resp.Content, start, end)
+	})
 	if err != nil {
-		return fmt.Errorf("替换代码失败: %v", err)
+		return err
 	}
 
-	fmt.Printf("代码补全完成，已更新文件: %s\n", e.FilePath)
-	return nil
+	return CommitEdit(e, newContent, client.ModelName())
 }
 
 func (s *CodeStrategy) GetName() string {
 	return "CodeStrategy"
 }
 
-func replaceCodeInFile(filePath string, fileText string, startLine, endLine int, completedCode string) error {
-	allLines := strings.Split(fileText, "\n")
-
-	var newLines []string
-	completedLines := strings.Split(completedCode, "\n")
-
-	if startLine == 0 && endLine == 0 {
-		// 替换整个文件
-		newLines = completedLines
-	} else {
-		// 替换指定范围的行
-		// 添加开始行之前的内容
-		if startLine > 1 {
-			newLines = append(newLines, allLines[:startLine-1]...)
-		}
+func (s *CodeStrategy) Description() string {
+	return "按要求修改选中的代码并写入文件"
+}
 
-		// 添加补全后的代码
-		newLines = append(newLines, completedLines...)
+// streamCompletion 流式执行代码补全，回答按事件的输出模式输出
+func streamCompletion(e *strategy.Event, client openai.ChatModel, req openai.ChatRequest) (*openai.ChatResponse, error) {
+	out := e.Out()
+	resp, err := client.Stream(context.Background(), req, out.Token)
+	if err != nil {
+		return nil, errcode.Wrap(errcode.Provider, err)
+	}
+	out.Answer(output.Answer{
+		Content:  resp.Content,
+		Provider: e.ResolveProvider(),
+		Model:    client.ModelName(),
+		Usage:    resp.Usage,
+	})
+	return resp, nil
+}
 
-		// 添加结束行之后的内容
-		if endLine < len(allLines) {
-			newLines = append(newLines, allLines[endLine:]...)
+// applyAnswer 按返回格式将大模型的回答应用到原文件内容，返回修改后的完整内容
+// 结构化修改（search-replace、diff）始终基于原文件应用，修复时大模型重新返回的修改同样基于原文件
+func applyAnswer(e *strategy.Event, format, answer string, start, end int) (string, error) {
+	var edits []patchEdit
+	var err error
+	switch format {
+	case rule.FormatSearchReplace:
+		edits, err = parseSearchReplace(answer)
+	case rule.FormatDiff:
+		edits, err = parseUnifiedDiff(answer)
+	default:
+		code, err := extract.Code(answer, e.Language)
+		if err != nil {
+			return "", errcode.Wrap(errcode.Extract, fmt.Errorf("提取代码失败，未写入文件: %w", err))
 		}
+		return ReplaceCode(e.FileText, start, end, code), nil
 	}
-
-	// 写入新内容
-	newContent := strings.Join(newLines, "\n")
-	err := os.WriteFile(filePath, []byte(newContent), 0644)
 	if err != nil {
-		return fmt.Errorf("写入文件失败: %v", err)
+		return "", errcode.Wrap(errcode.Patch, fmt.Errorf("解析修改失败: %w", err))
 	}
+	newContent, err := applyEdits(e.FileText, edits, e.SelectionStartLine)
+	if err != nil {
+		return "", errcode.Wrap(errcode.Patch, fmt.Errorf("应用修改失败: %w", err))
+	}
+	e.Out().Infof("\n已解析 %d 个修改块\n", len(edits))
+	return newContent, nil
+}
 
-	return nil
+// ReplaceCode 将补全后的代码替换到文件内容的 [start, end) 字节范围，返回替换后的完整内容
+func ReplaceCode(fileText string, start, end int, completedCode string) string {
+	selected := fileText[start:end]
+	// 选中内容以换行结尾（如整行选中到下一行行首）时，补全结果也需要保留换行
+	if strings.HasSuffix(selected, "\n") && !strings.HasSuffix(completedCode, "\n") {
+		completedCode += "\n"
+	}
+	return fileText[:start] + completedCode + fileText[end:]
 }
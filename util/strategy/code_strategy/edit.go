@@ -0,0 +1,174 @@
+package code_strategy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/diff"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/fsutil"
+	"github.com/MenciusCheng/go-cli/util/journal"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"github.com/MenciusCheng/go-cli/util/output"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CommitEdit 根据事件参数预览、确认并写入修改后的文件内容
+func CommitEdit(e *strategy.Event, newContent, model string) error {
+	out := e.Out()
+	if e.DryRun {
+		out.Infof("\n=== 修改预览（dry-run，不写入文件） ===\n")
+		printDiff(out, e.FilePath, e.FileText, newContent)
+		emitEdits(out, e.FilePath, e.FileText, newContent, false)
+		return nil
+	}
+
+	if e.Preview {
+		var ok bool
+		var err error
+		newContent, ok, err = confirmEdit(out, e.FilePath, e.FileText, newContent)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			out.Infof("已取消修改，文件未变更\n")
+			return nil
+		}
+	}
+
+	out.Infof("\n=== 正在替换代码 ===\n")
+	applied, err := applyEdit(e, newContent, model)
+	if err != nil {
+		return fmt.Errorf("替换代码失败: %w", err)
+	}
+	if !applied {
+		out.Infof("已取消修改，文件未变更\n")
+		return nil
+	}
+
+	out.Infof("代码补全完成，已更新文件: %s（可使用 go-cli undo 撤销）\n", e.FilePath)
+	return nil
+}
+
+// applyEdit 将新内容写入事件对应的文件，并记录到编辑历史中以便撤销，返回是否已写入
+// 如果文件在补全期间被修改，会尝试三方合并，合并冲突时放弃写入；
+// 合并结果与校验、确认过的内容不同，写入前重新校验，预览模式下重新确认
+func applyEdit(e *strategy.Event, newContent, model string) (bool, error) {
+	info, err := os.Stat(e.FilePath)
+	if err != nil {
+		return false, fmt.Errorf("读取文件失败: %w", err)
+	}
+	original, err := os.ReadFile(e.FilePath)
+	if err != nil {
+		return false, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	out := e.Out()
+	if current := string(original); current != e.FileText {
+		merged, err := diff.Merge3(e.FileText, newContent, current)
+		if err != nil {
+			var conflict *diff.ConflictError
+			if !errors.As(err, &conflict) {
+				return false, err
+			}
+			msg := fmt.Sprintf("文件 %s 在补全期间被修改", e.FilePath)
+			if !e.FileModTime.IsZero() {
+				msg += fmt.Sprintf("（修改时间 %s -> %s）", e.FileModTime.Format(time.TimeOnly), info.ModTime().Format(time.TimeOnly))
+			}
+			msg += fmt.Sprintf("，且%s，已放弃写入", conflict)
+			if path, saveErr := saveRejected(e.FilePath, newContent); saveErr == nil {
+				msg += fmt.Sprintf("，补全结果已保存到 %s", path)
+			}
+			return false, errcode.Wrap(errcode.Conflict, errors.New(msg))
+		}
+		out.Infof("检测到文件在补全期间被修改，已自动合并 %s\n", e.FilePath)
+		// 合并结果不再交给大模型修复，校验未通过时放弃写入
+		check := *e
+		check.RepairRounds = 0
+		if merged, err = ValidateWithRepair(&check, merged, nil); err != nil {
+			return false, err
+		}
+		if e.Preview {
+			var ok bool
+			if merged, ok, err = confirmEdit(out, e.FilePath, current, merged); err != nil || !ok {
+				return false, err
+			}
+		}
+		newContent = merged
+	}
+
+	if err := replaceCodeInFile(e.FilePath, newContent); err != nil {
+		return false, err
+	}
+	// edit hook 可能再次修改文件（如 goimports），编辑历史记录最终的内容，保证可以撤销
+	if err := e.Edited(e.FilePath); err != nil {
+		return false, err
+	}
+	if data, err := os.ReadFile(e.FilePath); err == nil {
+		newContent = string(data)
+	}
+	emitEdits(out, e.FilePath, string(original), newContent, true)
+
+	path, err := filepath.Abs(e.FilePath)
+	if err != nil {
+		path = e.FilePath
+	}
+	err = journal.New().Record(journal.Entry{
+		Path:     path,
+		Original: original,
+		New:      []byte(newContent),
+		Prompt:   e.Prompt,
+		Model:    model,
+	})
+	if err != nil {
+		// 记录失败不影响本次修改
+		logger.Warnf("记录编辑历史失败: %v\n", err)
+	} else if len(original)+len(newContent) > journal.MaxEntrySize {
+		logger.Warnf("文件过大，编辑历史未保存内容，无法通过 go-cli undo 撤销本次修改\n")
+	}
+	return true, nil
+}
+
+// replaceCodeInFile 将新内容原子写入文件，保留原文件权限
+func replaceCodeInFile(filePath string, newContent string) error {
+	err := fsutil.WriteFileAtomic(filePath, []byte(newContent), 0644)
+	if err != nil {
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	return nil
+}
+
+// emitEdits 结构化输出时按行计算修改范围并逐个输出，便于编辑器插件定位修改
+func emitEdits(out *output.Writer, filePath, oldContent, newContent string, applied bool) {
+	if !out.Structured() {
+		return
+	}
+	newLines := diff.SplitLines(newContent)
+	for _, h := range diff.Hunks(diff.Lines(diff.SplitLines(oldContent), newLines), 0) {
+		out.Edit(output.Edit{
+			FilePath:     filePath,
+			StartLine:    h.AStart + 1,
+			EndLine:      h.AStart + h.ALen,
+			NewStartLine: h.BStart + 1,
+			NewEndLine:   h.BStart + h.BLen,
+			Text:         strings.Join(newLines[h.BStart:h.BStart+h.BLen], "\n"),
+			Applied:      applied,
+		})
+	}
+}
+
+// saveRejected 将无法写入的补全结果保存到临时文件，返回文件路径
+func saveRejected(filePath, content string) (string, error) {
+	f, err := os.CreateTemp("", "go-cli-rejected-*"+filepath.Ext(filePath))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,171 @@
+package code_strategy
+
+import (
+	"context"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/budget"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/extract"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/util/output"
+	"github.com/MenciusCheng/go-cli/util/renderer"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+)
+
+// fimMaxTokens FIM 补全的最大输出 token 数
+const fimMaxTokens = 1024
+
+func NewInsertStrategy() strategy.Strategy {
+	return &InsertStrategy{}
+}
+
+// InsertStrategy 未选中代码时，在光标处插入补全的代码
+type InsertStrategy struct {
+}
+
+func (s *InsertStrategy) CanHandle(e *strategy.Event) strategy.Match {
+	// 未选中代码且提供了光标位置
+	switch {
+	case e.SelectedText != "":
+		return strategy.Reject("选中了代码")
+	case e.FilePath == "":
+		return strategy.Reject("未指定文件路径")
+	}
+	_, ok, err := e.CaretOffset()
+	switch {
+	case err != nil:
+		return strategy.Reject(fmt.Sprintf("光标位置无效: %v", err))
+	case !ok:
+		return strategy.Reject("未提供光标位置")
+	}
+	return strategy.Accept(strategy.ScoreSpecific, "未选中代码且提供了光标位置")
+}
+
+func (s *InsertStrategy) Handle(e *strategy.Event) error {
+	out := e.Out()
+	out.Infof("策略名称: %s\n", s.GetName())
+	out.Infof("光标处插入代码\n")
+
+	tmpl, err := e.LoadTemplate(rule.InsertRule)
+	if err != nil {
+		return err
+	}
+	repairTmpl, err := e.LoadTemplate(rule.RepairRule)
+	if err != nil {
+		return err
+	}
+
+	client, err := e.NewChatModel()
+	if err != nil {
+		return err
+	}
+
+	offset, _, err := e.CaretOffset()
+	if err != nil {
+		return err
+	}
+
+	eMap := e.ToMapByJSON()
+	eMap["prefix"] = ""
+	eMap["suffix"] = ""
+	render := renderer.New()
+	base, err := render.RenderString(tmpl.Body, eMap)
+	if err != nil {
+		return errcode.Wrap(errcode.Template, fmt.Errorf("渲染模板失败: %w", err))
+	}
+	// 光标前后的内容按模型的上下文窗口截取
+	caret := budget.Caret(e.FileText[:offset], e.FileText[offset:], e.ContextTokens(client, tmpl, base), e.MaxContextSize, nil)
+	if summary := caret.Summary(); summary != "" {
+		out.Infof("%s\n", summary)
+	}
+	prefix, suffix := caret.Prefix, caret.Suffix
+	eMap["prefix"] = prefix
+	eMap["suffix"] = suffix
+
+	// 渲染模板
+	prompt, err := render.RenderString(tmpl.Body, eMap)
+	if err != nil {
+		return errcode.Wrap(errcode.Template, fmt.Errorf("渲染模板失败: %w", err))
+	}
+	messages := tmpl.Messages(prompt)
+
+	var answer, code string
+	fimModel, isFIM := client.(openai.FIMModel)
+	if isFIM && client.Capabilities().FIM && e.Prompt == "" {
+		// 没有补全要求时使用 FIM 接口，直接根据前后文补全
+		out.Infof("\n=== 正在执行 FIM 补全 ===\n")
+		maxTokens := tmpl.MaxTokens
+		if maxTokens == 0 {
+			maxTokens = fimMaxTokens
+		}
+		resp, err := fimModel.StreamFIM(context.Background(), openai.FIMRequest{
+			Prefix:      prefix,
+			Suffix:      suffix,
+			Temperature: tmpl.TemperatureOrDefault(),
+			MaxTokens:   maxTokens,
+		}, out.Token)
+		if err != nil {
+			return errcode.Wrap(errcode.Provider, err)
+		}
+		out.Answer(output.Answer{
+			Content:  resp.Content,
+			Provider: e.ResolveProvider(),
+			Model:    client.ModelName(),
+			Usage:    resp.Usage,
+		})
+		answer, code = resp.Content, resp.Content
+	} else {
+		out.Prompt(prompt)
+
+		out.Infof("\n=== 正在执行代码补全 ===\n")
+		resp, err := streamCompletion(e, client, tmpl.Request(messages))
+		if err != nil {
+			return err
+		}
+		answer = resp.Content
+		code, err = extract.Code(resp.Content, e.Language)
+		if err != nil {
+			return errcode.Wrap(errcode.Extract, fmt.Errorf("提取代码失败，未写入文件: %w", err))
+		}
+	}
+
+	if code == "" {
+		return errcode.Wrap(errcode.Extract, fmt.Errorf("大模型未返回任何代码"))
+	}
+	newContent := e.FileText[:offset] + code + e.FileText[offset:]
+
+	// 校验失败时统一使用对话接口修复
+	newContent, err = ValidateWithRepair(e, newContent, func(problems string) (string, error) {
+		repairPrompt, err := render.RenderString(repairTmpl.Body, map[string]interface{}{"problems": problems})
+		if err != nil {
+			return "", errcode.Wrap(errcode.Template, fmt.Errorf("渲染模板失败: %w", err))
+		}
+		messages = append(messages,
+			openai.Message{Role: openai.RoleAssistant, Content: answer},
+			openai.Message{Role: openai.RoleUser, Content: repairPrompt},
+		)
+		resp, err := streamCompletion(e, client, tmpl.Request(messages))
+		if err != nil {
+			return "", err
+		}
+		answer = resp.Content
+		code, err := extract.Code(resp.Content, e.Language)
+		if err != nil {
+			return "", errcode.Wrap(errcode.Extract, fmt.Errorf("提取代码失败，未写入文件: %w", err))
+		}
+		return e.FileText[:offset] + code + e.FileText[offset:], nil
+	})
+	if err != nil {
+		return err
+	}
+	return CommitEdit(e, newContent, client.ModelName())
+}
+
+func (s *InsertStrategy) GetName() string {
+	return "InsertStrategy"
+}
+
+func (s *InsertStrategy) Description() string {
+	return "未选中代码时，在光标处插入补全的代码，支持 FIM 接口"
+}
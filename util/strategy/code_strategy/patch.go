@@ -0,0 +1,317 @@
+package code_strategy
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// patchEdit 一处结构化修改：将文件中与 Search 匹配的行替换为 Replace
+type patchEdit struct {
+	Search  []string // 需要查找的原始代码行
+	Replace []string // 替换后的代码行
+	Line    int      // 原始代码在文件中的参考行号（从 1 开始），为 0 时表示未知
+}
+
+// PatchError 修改块无法应用到文件
+type PatchError struct {
+	Index   int      // 第几个修改块，从 1 开始
+	Reason  string   // 失败原因
+	Search  []string // 修改块中的原始代码
+	Nearest int      // 文件中最相近位置的行号，为 0 时表示没有相近的位置
+}
+
+func (e *PatchError) Error() string {
+	msg := fmt.Sprintf("第 %d 个修改块无法应用: %s", e.Index, e.Reason)
+	if e.Nearest > 0 {
+		msg += fmt.Sprintf("（最相近的位置在第 %d 行）", e.Nearest)
+	}
+	if len(e.Search) > 0 {
+		msg += "\n原始代码:\n" + strings.Join(e.Search, "\n")
+	}
+	return msg
+}
+
+var (
+	searchMarker  = regexp.MustCompile(`^<{5,}\s*SEARCH\s*$`)
+	dividerMarker = regexp.MustCompile(`^={5,}\s*$`)
+	replaceMarker = regexp.MustCompile(`^>{5,}\s*REPLACE\s*$`)
+	hunkHeader    = regexp.MustCompile(`^@@\s*-(\d+)(?:,(\d+))?\s+\+\d+(?:,(\d+))?\s*@@`)
+)
+
+// parseSearchReplace 解析 SEARCH/REPLACE 格式的修改块，修改块之外的内容会被忽略
+//
+//	<<<<<<< SEARCH
+//	原始代码
+//	=======
+//	修改后的代码
+//	>>>>>>> REPLACE
+func parseSearchReplace(answer string) ([]patchEdit, error) {
+	var edits []patchEdit
+	var cur *patchEdit
+	inReplace := false
+	for i, line := range strings.Split(answer, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case searchMarker.MatchString(line):
+			if cur != nil {
+				return nil, fmt.Errorf("第 %d 行: 上一个修改块缺少结束标记 >>>>>>> REPLACE", i+1)
+			}
+			cur = &patchEdit{}
+			inReplace = false
+		case cur != nil && !inReplace && dividerMarker.MatchString(line):
+			inReplace = true
+		case cur != nil && inReplace && replaceMarker.MatchString(line):
+			edits = append(edits, *cur)
+			cur = nil
+		case cur == nil:
+			// 修改块之外的内容，如代码块标记或说明文字
+		case inReplace:
+			cur.Replace = append(cur.Replace, line)
+		default:
+			cur.Search = append(cur.Search, line)
+		}
+	}
+	if cur != nil {
+		return nil, fmt.Errorf("第 %d 个修改块不完整，缺少分隔标记 ======= 或结束标记 >>>>>>> REPLACE", len(edits)+1)
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("返回结果中未找到 SEARCH/REPLACE 修改块")
+	}
+	return edits, nil
+}
+
+// parseUnifiedDiff 解析统一格式差异（unified diff）中的修改块
+// 以 "@@" 开头的行开始一个修改块，行号可以省略；遇到代码块标记或其它内容时结束当前修改块
+// "--- " 和 "+++ " 开头的行见 isFileHeader，不是文件头时是以 "-- " 开头的删除行或以 "++ " 开头的新增行，如 SQL 注释
+func parseUnifiedDiff(answer string) ([]patchEdit, error) {
+	var edits []patchEdit
+	var cur *patchEdit
+	blank := 0               // 修改块末尾连续的空行数，模型常在修改块之间输出空行
+	oldLeft, newLeft := 0, 0 // 修改块头中声明的剩余行数，未声明时为 0
+	finish := func() {
+		if cur == nil {
+			return
+		}
+		cur.Search = cur.Search[:len(cur.Search)-blank]
+		cur.Replace = cur.Replace[:len(cur.Replace)-blank]
+		if len(cur.Search) > 0 || len(cur.Replace) > 0 {
+			edits = append(edits, *cur)
+		}
+		cur, blank = nil, 0
+	}
+
+	lines := strings.Split(answer, "\n")
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "@@") {
+			finish()
+			cur = &patchEdit{}
+			oldLeft, newLeft = 0, 0
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				cur.Line, _ = strconv.Atoi(m[1])
+				oldLeft, newLeft = hunkCount(m[2]), hunkCount(m[3])
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch {
+		case line == "":
+			cur.Search = append(cur.Search, "")
+			cur.Replace = append(cur.Replace, "")
+			oldLeft--
+			newLeft--
+			blank++
+			continue
+		case strings.HasPrefix(line, "```"):
+			finish()
+		case isFileHeader(lines, i, oldLeft <= 0 && newLeft <= 0):
+			// 下一个文件的文件头
+			finish()
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		case line[0] == ' ':
+			cur.Search = append(cur.Search, line[1:])
+			cur.Replace = append(cur.Replace, line[1:])
+			oldLeft--
+			newLeft--
+		case line[0] == '-':
+			cur.Search = append(cur.Search, line[1:])
+			oldLeft--
+		case line[0] == '+':
+			cur.Replace = append(cur.Replace, line[1:])
+			newLeft--
+		default:
+			finish()
+		}
+		blank = 0
+	}
+	finish()
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("返回结果中未找到以 @@ 开头的差异修改块")
+	}
+	return edits, nil
+}
+
+// isFileHeader 第 i 行是否为文件头：以 "--- " 开头且下一行以 "+++ " 开头，
+// 并且修改块头中声明的行数已经用完（done，未声明行数时也为 true），或文件头之后紧跟着下一个修改块头
+func isFileHeader(lines []string, i int, done bool) bool {
+	if !strings.HasPrefix(lines[i], "--- ") || i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+		return false
+	}
+	return done || i+2 < len(lines) && strings.HasPrefix(lines[i+2], "@@")
+}
+
+// hunkCount 修改块头中的行数，省略时为 1
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// applyEdits 依次将修改块应用到文本，near 为参考行号（如选中区域的开始行），
+// 同一段代码在文件中出现多次时，选择离参考行号最近的位置
+func applyEdits(text string, edits []patchEdit, near int) (string, error) {
+	trailingNewline := strings.HasSuffix(text, "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+
+	shift := 0 // 前面的修改块引起的行号偏移
+	for i, edit := range edits {
+		hint := near
+		if edit.Line > 0 {
+			hint = edit.Line + shift
+		}
+
+		if len(edit.Search) == 0 {
+			// 没有原始代码时只能根据行号插入
+			if edit.Line == 0 {
+				return "", &PatchError{Index: i + 1, Reason: "修改块没有需要查找的原始代码，无法确定修改位置"}
+			}
+			pos := min(max(hint-1, 0), len(lines))
+			lines = splice(lines, pos, 0, edit.Replace)
+			shift += len(edit.Replace)
+			continue
+		}
+
+		pos, replace, ok := findBlock(lines, edit.Search, edit.Replace, hint)
+		if !ok {
+			return "", &PatchError{
+				Index:   i + 1,
+				Reason:  "未在文件中找到匹配的原始代码",
+				Search:  edit.Search,
+				Nearest: nearestBlock(lines, edit.Search),
+			}
+		}
+		lines = splice(lines, pos, len(edit.Search), replace)
+		shift += len(replace) - len(edit.Search)
+	}
+
+	result := strings.Join(lines, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// lineMatchers 按严格程度排列的行匹配规则：完全一致、忽略行尾空白、忽略首尾空白
+var lineMatchers = []func(a, b string) bool{
+	func(a, b string) bool { return a == b },
+	func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") },
+	func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) },
+}
+
+// findBlock 在 lines 中查找与 search 匹配的位置，依次放宽匹配规则
+// 忽略缩进匹配成功时，按文件中的实际缩进调整 replace 的缩进
+func findBlock(lines, search, replace []string, hint int) (int, []string, bool) {
+	for level, match := range lineMatchers {
+		best := -1
+		for pos := 0; pos+len(search) <= len(lines); pos++ {
+			if !blockMatches(lines[pos:pos+len(search)], search, match) {
+				continue
+			}
+			if best < 0 || abs(pos+1-hint) < abs(best+1-hint) {
+				best = pos
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		if level == len(lineMatchers)-1 {
+			replace = reindent(lines[best:best+len(search)], search, replace)
+		}
+		return best, replace, true
+	}
+	return 0, nil, false
+}
+
+func blockMatches(lines, search []string, match func(a, b string) bool) bool {
+	for i := range search {
+		if !match(lines[i], search[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// nearestBlock 查找与 search 匹配行数最多的位置，返回行号，没有任何一行匹配时返回 0
+func nearestBlock(lines, search []string) int {
+	best, bestCount := 0, 0
+	for pos := range lines {
+		count := 0
+		for i := 0; i < len(search) && pos+i < len(lines); i++ {
+			if strings.TrimSpace(search[i]) != "" && strings.TrimSpace(lines[pos+i]) == strings.TrimSpace(search[i]) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = pos+1, count
+		}
+	}
+	return best
+}
+
+// reindent 将替换代码中与原始代码第一行非空代码相同的缩进，替换为该行在文件中的实际缩进
+func reindent(matched, search, replace []string) []string {
+	for i, line := range search {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		want, got := leadingSpace(matched[i]), leadingSpace(line)
+		result := make([]string, len(replace))
+		for j, r := range replace {
+			if strings.TrimSpace(r) != "" && strings.HasPrefix(r, got) {
+				r = want + r[len(got):]
+			}
+			result[j] = r
+		}
+		return result
+	}
+	return replace
+}
+
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// splice 将 lines[pos:pos+n] 替换为 replace
+func splice(lines []string, pos, n int, replace []string) []string {
+	result := make([]string, 0, len(lines)-n+len(replace))
+	result = append(result, lines[:pos]...)
+	result = append(result, replace...)
+	return append(result, lines[pos+n:]...)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,268 @@
+package code_strategy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatEdits 将修改块格式化为便于比较的文本，nil 和空切片视为相同
+func formatEdits(edits []patchEdit) string {
+	var sb strings.Builder
+	for _, e := range edits {
+		fmt.Fprintf(&sb, "line=%d search=%q replace=%q\n", e.Line, strings.Join(e.Search, "\n"), strings.Join(e.Replace, "\n"))
+	}
+	return sb.String()
+}
+
+// edit 创建修改块，Search 和 Replace 按换行符分割，为空字符串时表示没有代码行
+func edit(line int, search, replace string) patchEdit {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, "\n")
+	}
+	return patchEdit{Line: line, Search: split(search), Replace: split(replace)}
+}
+
+func TestParseSearchReplace(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  string
+		want    []patchEdit
+		wantErr bool
+	}{
+		{
+			name:   "单个修改块",
+			answer: "说明\n```go\n<<<<<<< SEARCH\nfoo()\n=======\nbar()\n>>>>>>> REPLACE\n```\n",
+			want:   []patchEdit{edit(0, "foo()", "bar()")},
+		},
+		{
+			name:   "多个修改块",
+			answer: "<<<<<<< SEARCH\na\nb\n=======\nc\n>>>>>>> REPLACE\n\n<<<<<<< SEARCH\nd\n=======\ne\nf\n>>>>>>> REPLACE",
+			want:   []patchEdit{edit(0, "a\nb", "c"), edit(0, "d", "e\nf")},
+		},
+		{
+			name:   "删除代码",
+			answer: "<<<<<<< SEARCH\nfoo()\n=======\n>>>>>>> REPLACE",
+			want:   []patchEdit{edit(0, "foo()", "")},
+		},
+		{
+			name:   "CRLF 换行和更长的标记",
+			answer: "<<<<<<<< SEARCH\r\nfoo()\r\n========\r\nbar()\r\n>>>>>>>> REPLACE\r\n",
+			want:   []patchEdit{edit(0, "foo()", "bar()")},
+		},
+		{
+			name:   "替换代码中的分隔线",
+			answer: "<<<<<<< SEARCH\nfoo()\n=======\n=======\n>>>>>>> REPLACE",
+			want:   []patchEdit{edit(0, "foo()", "=======")},
+		},
+		{name: "缺少结束标记", answer: "<<<<<<< SEARCH\nfoo()\n=======\nbar()\n", wantErr: true},
+		{name: "缺少分隔标记", answer: "<<<<<<< SEARCH\nfoo()\n>>>>>>> REPLACE\n", wantErr: true},
+		{name: "修改块嵌套", answer: "<<<<<<< SEARCH\nfoo()\n<<<<<<< SEARCH\n", wantErr: true},
+		{name: "没有修改块", answer: "```go\nfoo()\n```", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSearchReplace(tt.answer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSearchReplace() 错误 = %v, 期望错误 %v", err, tt.wantErr)
+			}
+			if formatEdits(got) != formatEdits(tt.want) {
+				t.Errorf("parseSearchReplace() =\n%s期望\n%s", formatEdits(got), formatEdits(tt.want))
+			}
+		})
+	}
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  string
+		want    []patchEdit
+		wantErr bool
+	}{
+		{
+			name:   "带文件头和行号",
+			answer: "```diff\n--- a/main.go\n+++ b/main.go\n@@ -3,3 +3,3 @@\n a\n-b\n+c\n d\n```\n",
+			want:   []patchEdit{edit(3, "a\nb\nd", "a\nc\nd")},
+		},
+		{
+			name:   "省略行号",
+			answer: "@@ ... @@\n a\n-b\n+c",
+			want:   []patchEdit{edit(0, "a\nb", "a\nc")},
+		},
+		{
+			name:   "修改块之间的空行",
+			answer: "@@ -1 +1 @@\n-a\n+b\n\n\n@@ -9 +9 @@\n-c\n+d\n",
+			want:   []patchEdit{edit(1, "a", "b"), edit(9, "c", "d")},
+		},
+		{
+			name:   "修改块中的空行",
+			answer: "@@ -1,3 +1,3 @@\n a\n\n-b\n+c",
+			want:   []patchEdit{edit(1, "a\n\nb", "a\n\nc")},
+		},
+		{
+			name:   "忽略文件末尾没有换行的提示",
+			answer: "@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file",
+			want:   []patchEdit{edit(1, "a", "b")},
+		},
+		{
+			name:   "删除以 -- 开头的 SQL 注释",
+			answer: "@@ -1,2 +1,1 @@\n--- 旧的注释\n SELECT 1;",
+			want:   []patchEdit{edit(1, "-- 旧的注释\nSELECT 1;", "SELECT 1;")},
+		},
+		{
+			name:   "行数未用完时 --- 和 +++ 是修改内容",
+			answer: "@@ -1,2 +1,2 @@\n--- 旧的注释\n+++ 新的内容\n SELECT 1;",
+			want:   []patchEdit{edit(1, "-- 旧的注释\nSELECT 1;", "++ 新的内容\nSELECT 1;")},
+		},
+		{
+			name:   "行数用完后是下一个文件的文件头",
+			answer: "@@ -1 +1 @@\n-a\n+b\n--- a/other.go\n+++ b/other.go\n@@ -5 +5 @@\n-c\n+d",
+			want:   []patchEdit{edit(1, "a", "b"), edit(5, "c", "d")},
+		},
+		{
+			name:   "未声明行数时文件头后紧跟修改块头",
+			answer: "@@ @@\n-a\n+b\n--- a/other.go\n+++ b/other.go\n@@ @@\n-c\n+d",
+			want:   []patchEdit{edit(0, "a", "b"), edit(0, "c", "d")},
+		},
+		{
+			name:   "修改块后的说明文字",
+			answer: "@@ -1 +1 @@\n-a\n+b\n以上修改将 a 替换为 b",
+			want:   []patchEdit{edit(1, "a", "b")},
+		},
+		{name: "没有修改块", answer: "--- a/main.go\n+++ b/main.go\n", wantErr: true},
+		{name: "修改块为空", answer: "@@ -1 +1 @@\n```", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUnifiedDiff(tt.answer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUnifiedDiff() 错误 = %v, 期望错误 %v", err, tt.wantErr)
+			}
+			if formatEdits(got) != formatEdits(tt.want) {
+				t.Errorf("parseUnifiedDiff() =\n%s期望\n%s", formatEdits(got), formatEdits(tt.want))
+			}
+		})
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	const text = "func a() {\n\tfoo()\n}\n\nfunc b() {\n\tfoo()\n}\n"
+	tests := []struct {
+		name    string
+		text    string
+		edits   []patchEdit
+		near    int
+		want    string
+		nearest int // 期望失败时 PatchError 中最相近的行号
+		wantErr bool
+	}{
+		{
+			name:  "完全一致",
+			text:  "a\nb\nc\n",
+			edits: []patchEdit{edit(0, "b", "x\ny")},
+			want:  "a\nx\ny\nc\n",
+		},
+		{
+			name:  "保留文件末尾没有换行",
+			text:  "a\nb",
+			edits: []patchEdit{edit(0, "b", "c")},
+			want:  "a\nc",
+		},
+		{
+			name:  "忽略行尾空白",
+			text:  "a  \nb\t\n",
+			edits: []patchEdit{edit(0, "a\nb", "c")},
+			want:  "c\n",
+		},
+		{
+			name:  "忽略缩进时按文件的缩进调整替换代码",
+			text:  "func a() {\n\tif x {\n\t\tfoo()\n\t}\n}\n",
+			edits: []patchEdit{edit(0, "if x {\n\tfoo()\n}", "if x {\n\tbar()\n}")},
+			want:  "func a() {\n\tif x {\n\t\tbar()\n\t}\n}\n",
+		},
+		{
+			name:  "忽略缩进时缩进不同的行保持不变",
+			text:  "\t\ta\n",
+			edits: []patchEdit{edit(0, "    a", "    b\nc")},
+			want:  "\t\tb\nc\n",
+		},
+		{
+			name:  "完全一致优先于忽略空白",
+			text:  "  a\na\n",
+			edits: []patchEdit{edit(0, "a", "b")},
+			near:  1,
+			want:  "  a\nb\n",
+		},
+		{
+			name:  "多处匹配时选择离参考行号最近的位置",
+			text:  text,
+			edits: []patchEdit{edit(0, "\tfoo()", "\tbar()")},
+			near:  6,
+			want:  "func a() {\n\tfoo()\n}\n\nfunc b() {\n\tbar()\n}\n",
+		},
+		{
+			name:  "修改块的行号优先于参考行号",
+			text:  text,
+			edits: []patchEdit{edit(2, "\tfoo()", "\tbar()")},
+			near:  6,
+			want:  "func a() {\n\tbar()\n}\n\nfunc b() {\n\tfoo()\n}\n",
+		},
+		{
+			name:  "前面的修改块引起的行号偏移",
+			text:  text,
+			edits: []patchEdit{edit(1, "func a() {", "// a\nfunc a() {"), edit(6, "\tfoo()", "\tbar()")},
+			want:  "// a\nfunc a() {\n\tfoo()\n}\n\nfunc b() {\n\tbar()\n}\n",
+		},
+		{
+			name:  "没有原始代码时按行号插入",
+			text:  "a\nb\n",
+			edits: []patchEdit{edit(2, "", "x")},
+			want:  "a\nx\nb\n",
+		},
+		{
+			name:  "空文件",
+			text:  "",
+			edits: []patchEdit{edit(1, "", "a")},
+			want:  "a",
+		},
+		{
+			name:    "没有原始代码也没有行号",
+			text:    "a\n",
+			edits:   []patchEdit{edit(0, "", "x")},
+			wantErr: true,
+		},
+		{
+			name:    "未找到原始代码",
+			text:    "a\nb\nc\n",
+			edits:   []patchEdit{edit(0, "b\nx", "y")},
+			nearest: 2,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyEdits(tt.text, tt.edits, tt.near)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyEdits() 错误 = %v, 期望错误 %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var pe *PatchError
+				if !errors.As(err, &pe) {
+					t.Fatalf("applyEdits() 错误 = %v, 期望 *PatchError", err)
+				}
+				if pe.Nearest != tt.nearest {
+					t.Errorf("最相近的行号 = %d, 期望 %d", pe.Nearest, tt.nearest)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("applyEdits() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
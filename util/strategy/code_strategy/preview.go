@@ -0,0 +1,102 @@
+package code_strategy
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/diff"
+	"github.com/MenciusCheng/go-cli/util/output"
+	"github.com/MenciusCheng/go-cli/util/terminal"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// printDiff 输出原内容和新内容之间的差异，没有差异时返回 false
+func printDiff(out *output.Writer, filePath, oldContent, newContent string) bool {
+	unified := diff.Unified(oldContent, newContent, "a/"+filepath.ToSlash(filePath), "b/"+filepath.ToSlash(filePath))
+	if unified == "" {
+		out.Infof("补全结果与原文件内容相同\n")
+		return false
+	}
+	if out.Structured() {
+		out.Diff(unified)
+		return true
+	}
+	if terminal.ColorEnabled(os.Stdout) {
+		unified = diff.Colorize(unified)
+	}
+	fmt.Print(unified)
+	return true
+}
+
+// confirmEdit 展示差异并询问是否写入，返回最终确认写入的内容，用户拒绝时返回 false
+func confirmEdit(out *output.Writer, filePath, oldContent, newContent string) (string, bool, error) {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Println("\n=== 修改预览 ===")
+		if !printDiff(out, filePath, oldContent, newContent) {
+			return newContent, false, nil
+		}
+
+		fmt.Print("是否写入文件? [y]接受 / [n]拒绝 / [e]编辑: ")
+		input, err := reader.ReadString('\n')
+		if err != nil && input == "" {
+			return "", false, fmt.Errorf("读取输入失败: %w", err)
+		}
+
+		switch strings.TrimSpace(strings.ToLower(input)) {
+		case "y", "yes":
+			return newContent, true, nil
+		case "n", "no", "":
+			return "", false, nil
+		case "e", "edit":
+			edited, err := editInEditor(filePath, newContent)
+			if err != nil {
+				return "", false, err
+			}
+			newContent = edited
+		default:
+			fmt.Println("无效的输入，请输入 y、n 或 e")
+		}
+	}
+}
+
+// editInEditor 使用 $VISUAL / $EDITOR 编辑内容，返回编辑后的内容
+func editInEditor(filePath, content string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// 保留扩展名，便于编辑器识别语法
+	tmp, err := os.CreateTemp("", "go-cli-*"+filepath.Ext(filePath))
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	tmp.Close()
+
+	// 编辑器命令可能带参数，例如 "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], tmp.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("运行编辑器 %s 失败: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return "", fmt.Errorf("读取编辑结果失败: %w", err)
+	}
+	return string(edited), nil
+}
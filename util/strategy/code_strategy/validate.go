@@ -0,0 +1,154 @@
+package code_strategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultRepairRounds 校验失败时默认的修复轮数
+const DefaultRepairRounds = 2
+
+// RepairFunc 根据校验错误重新生成修改后的完整文件内容
+type RepairFunc func(problems string) (string, error)
+
+// ValidateWithRepair 校验修改后的内容，失败时将错误反馈给大模型修复，最多修复 e.RepairRounds 轮
+// 校验在写入文件前进行，始终未通过时返回错误，原文件保持不变；只校验 Go 文件，其他文件输出提示后跳过
+func ValidateWithRepair(e *strategy.Event, content string, repair RepairFunc) (string, error) {
+	if !e.Validate && !e.Build && !e.Vet {
+		return content, nil
+	}
+	out := e.Out()
+	if !strings.HasSuffix(e.FilePath, ".go") {
+		out.Infof("警告: --validate、--build、--vet 只支持 Go 文件，%s 未校验\n", e.FilePath)
+		return content, nil
+	}
+	rounds := e.RepairRounds
+	if rounds < 0 {
+		rounds = 0
+	}
+
+	for round := 0; ; round++ {
+		out.Infof("\n=== 正在校验代码 ===\n")
+		formatted, problems, err := validateGo(e, content)
+		if err != nil {
+			return "", err
+		}
+		if problems == "" {
+			out.Infof("校验通过\n")
+			return formatted, nil
+		}
+		out.Infof("校验未通过:\n%s\n", problems)
+
+		if round >= rounds {
+			msg := fmt.Sprintf("补全结果经过 %d 轮修复仍未通过校验，已放弃写入，原文件未变更", rounds)
+			if path, saveErr := saveRejected(e.FilePath, content); saveErr == nil {
+				msg += fmt.Sprintf("，补全结果已保存到 %s", path)
+			}
+			return "", errcode.Wrap(errcode.Validation, fmt.Errorf("%s", msg))
+		}
+
+		out.Infof("\n=== 正在修复代码（第 %d/%d 轮） ===\n", round+1, rounds)
+		content, err = repair(problems)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// validateGo 校验 Go 代码：语法解析和 gofmt 格式化，可选 go build / go vet
+// 返回格式化后的内容和发现的问题，问题为空表示校验通过
+func validateGo(e *strategy.Event, content string) (string, string, error) {
+	fset := token.NewFileSet()
+	if _, err := parser.ParseFile(fset, filepath.Base(e.FilePath), content, parser.AllErrors|parser.ParseComments); err != nil {
+		return "", fmt.Sprintf("语法错误:\n%v", err), nil
+	}
+	formattedBytes, err := format.Source([]byte(content))
+	if err != nil {
+		return "", fmt.Sprintf("gofmt 格式化失败:\n%v", err), nil
+	}
+	formatted := string(formattedBytes)
+
+	if e.Build {
+		if problems, err := goCommand(e.FilePath, formatted, "build"); err != nil || problems != "" {
+			return "", problems, err
+		}
+	}
+	if e.Vet {
+		if problems, err := goCommand(e.FilePath, formatted, "vet"); err != nil || problems != "" {
+			return "", problems, err
+		}
+	}
+	return formatted, "", nil
+}
+
+// goCommand 在文件所在包执行 go build / go vet，通过 -overlay 使用修改后的内容而不写入原文件
+func goCommand(filePath, content, command string) (string, error) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return "", fmt.Errorf("未找到 go 命令，无法执行 go %s: %v", command, err)
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "go-cli-validate-*")
+	if err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	replacement := filepath.Join(tmpDir, filepath.Base(absPath))
+	if err := os.WriteFile(replacement, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	overlay, err := json.Marshal(map[string]map[string]string{
+		"Replace": {absPath: replacement},
+	})
+	if err != nil {
+		return "", err
+	}
+	overlayPath := filepath.Join(tmpDir, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0644); err != nil {
+		return "", fmt.Errorf("写入 overlay 文件失败: %w", err)
+	}
+
+	var args []string
+	switch {
+	case command == "build" && strings.HasSuffix(absPath, "_test.go"):
+		// 测试文件不参与 go build，改为只编译测试
+		args = []string{"test", "-overlay", overlayPath, "-count=1", "-run", "^$", "."}
+	case command == "build":
+		args = []string{"build", "-overlay", overlayPath, "-o", os.DevNull, "."}
+	default:
+		args = []string{command, "-overlay", overlayPath, "."}
+	}
+
+	cmd := exec.Command(goBin, args...)
+	cmd.Dir = filepath.Dir(absPath)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			// 将临时文件路径还原为原文件名，便于大模型理解
+			problems := strings.ReplaceAll(output.String(), replacement, filepath.Base(absPath))
+			if rel, err := filepath.Rel(cmd.Dir, replacement); err == nil {
+				problems = strings.ReplaceAll(problems, rel, filepath.Base(absPath))
+			}
+			return fmt.Sprintf("go %s 失败:\n%s", command, strings.TrimSpace(problems)), nil
+		}
+		return "", fmt.Errorf("执行 go %s 失败: %v", command, err)
+	}
+	return "", nil
+}
@@ -12,22 +12,28 @@ func NewEchoStrategy() Strategy {
 // 示例策略实现
 type EchoStrategy struct{}
 
-func (s *EchoStrategy) CanHandle(e *Event) bool {
-	return true
+func (s *EchoStrategy) CanHandle(e *Event) Match {
+	return Accept(ScoreFallback, "兜底策略，始终可以处理")
 }
 
 func (s *EchoStrategy) Handle(e *Event) error {
-	fmt.Printf("策略名称: %s\n", s.GetName())
-	fmt.Printf("命中默认策略，事件参数：")
+	out := e.Out()
+	out.Infof("策略名称: %s\n", s.GetName())
+	out.Infof("命中默认策略，事件参数：")
 	// 将事件转换为 JSON 格式并打印
 	eventJSON, err := json.MarshalIndent(e, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal event to JSON: %w", err)
 	}
-	fmt.Printf("%s\n", string(eventJSON))
+	// 事件参数是策略的结果，输出到标准输出，-q 时也保留
+	out.Raw(string(eventJSON))
 	return nil
 }
 
 func (s *EchoStrategy) GetName() string {
 	return "EchoStrategy"
 }
+
+func (s *EchoStrategy) Description() string {
+	return "输出事件参数，用于调试 IDE 传入的参数"
+}
@@ -0,0 +1,117 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/config"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// defaultHookTimeout hook 的默认超时时间
+const defaultHookTimeout = 60 * time.Second
+
+// RunHooks 运行配置文件中声明的 hook
+//   - before：选择策略前运行，失败时中止执行
+//   - after：策略执行后运行，失败时只输出警告
+//   - edit：策略写入文件后运行，如执行 goimports，失败时只输出警告
+//
+// --explain 时不运行 hook
+func RunHooks(next Handler) Handler {
+	return func(e *Event) error {
+		if len(e.Hooks) == 0 || e.Explain {
+			return next(e)
+		}
+		for _, h := range e.Hooks {
+			if h.When != config.HookBefore || !hookMatches(h, e.FilePath) {
+				continue
+			}
+			if err := runHook(h, hookEnv(e, h.When, e.FilePath, nil)); err != nil {
+				return errcode.Wrap(errcode.Hook, err)
+			}
+		}
+
+		e.EditHooks = append(e.EditHooks, func(e *Event, path string) error {
+			for _, h := range e.Hooks {
+				if h.When != config.HookEdit || !hookMatches(h, path) {
+					continue
+				}
+				if err := runHook(h, hookEnv(e, h.When, path, nil)); err != nil {
+					logger.Warnf("%v\n", err)
+				}
+			}
+			return nil
+		})
+
+		err := next(e)
+		for _, h := range e.Hooks {
+			if h.When != config.HookAfter || !hookMatches(h, e.FilePath) {
+				continue
+			}
+			if hookErr := runHook(h, hookEnv(e, h.When, e.FilePath, err)); hookErr != nil {
+				logger.Warnf("%v\n", hookErr)
+			}
+		}
+		return err
+	}
+}
+
+// hookMatches 判断 hook 是否需要对该文件运行，未配置 files 时始终运行
+func hookMatches(h config.Hook, path string) bool {
+	if h.Files == "" {
+		return true
+	}
+	if path == "" {
+		return false
+	}
+	ok, _ := filepath.Match(h.Files, filepath.Base(path))
+	return ok
+}
+
+// hookEnv hook 命令的环境变量，在当前环境的基础上增加事件信息
+func hookEnv(e *Event, when, path string, err error) []string {
+	env := append(os.Environ(),
+		"GO_CLI_HOOK="+when,
+		"GO_CLI_STRATEGY="+e.Strategy,
+		"GO_CLI_FILE_PATH="+path,
+		"GO_CLI_PROMPT="+e.Prompt,
+	)
+	if err != nil {
+		env = append(env, "GO_CLI_ERROR="+err.Error())
+	}
+	return env
+}
+
+// runHook 执行 hook 命令，命令的输出写入标准错误，避免影响标准输出中的回答和结构化输出
+func runHook(h config.Hook, env []string) error {
+	timeout := defaultHookTimeout
+	if h.Timeout > 0 {
+		timeout = time.Duration(h.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", h.Run)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", h.Run)
+	}
+	cmd.Env = env
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	logger.Debugf("运行 %s hook: %s\n", h.When, h.Run)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s hook %q 超时（%s）", h.When, h.Run, timeout)
+		}
+		return fmt.Errorf("%s hook %q 执行失败: %v", h.When, h.Run, err)
+	}
+	return nil
+}
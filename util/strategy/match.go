@@ -0,0 +1,113 @@
+package strategy
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/output"
+	"sort"
+	"strings"
+)
+
+// 匹配得分，多个策略都能处理时选择得分最高的，得分相同时按注册顺序
+const (
+	ScoreFallback = 1   // 兜底策略，如 EchoStrategy
+	ScoreDefault  = 10  // 通用策略，如 AskAnyStrategy
+	ScoreSpecific = 50  // 依赖选中代码、光标位置等上下文的策略
+	ScoreExplicit = 100 // 用户通过参数明确要求的策略，如 -i 交互式对话
+)
+
+// Match 策略的匹配结果
+type Match struct {
+	Score  int    // 匹配得分，0 表示不能处理
+	Reason string // 匹配或不匹配的原因，用于 --explain
+}
+
+// OK 是否能处理
+func (m Match) OK() bool {
+	return m.Score > 0
+}
+
+// Accept 能处理该事件
+func Accept(score int, reason string) Match {
+	return Match{Score: score, Reason: reason}
+}
+
+// Reject 不能处理该事件
+func Reject(reason string) Match {
+	return Match{Reason: reason}
+}
+
+// Strategies 已注册的策略，按注册顺序排列
+func (sm *StrategyManager) Strategies() []Strategy {
+	return sm.strategies
+}
+
+// Find 根据名称查找策略，不区分大小写，可以省略 Strategy 后缀
+func (sm *StrategyManager) Find(name string) (Strategy, bool) {
+	for _, s := range sm.strategies {
+		if strings.EqualFold(s.GetName(), name) || strings.EqualFold(s.GetName(), name+"Strategy") {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// candidates 计算所有策略的匹配结果，按得分从高到低排列，得分相同时保持注册顺序
+func (sm *StrategyManager) candidates(e *Event) ([]Strategy, []Match) {
+	strategies := make([]Strategy, len(sm.strategies))
+	copy(strategies, sm.strategies)
+	matches := make(map[Strategy]Match, len(strategies))
+	for _, s := range strategies {
+		matches[s] = s.CanHandle(e)
+	}
+	sort.SliceStable(strategies, func(i, j int) bool {
+		return matches[strategies[i]].Score > matches[strategies[j]].Score
+	})
+	result := make([]Match, len(strategies))
+	for i, s := range strategies {
+		result[i] = matches[s]
+	}
+	return strategies, result
+}
+
+// selectStrategy 选择处理事件的策略：指定了 --strategy 时使用指定的策略，否则选择得分最高的策略
+func (sm *StrategyManager) selectStrategy(e *Event) (Strategy, error) {
+	if e.Strategy != "" {
+		s, ok := sm.Find(e.Strategy)
+		if !ok {
+			names := make([]string, 0, len(sm.strategies))
+			for _, s := range sm.strategies {
+				names = append(names, s.GetName())
+			}
+			return nil, errcode.Wrap(errcode.InvalidArgument,
+				fmt.Errorf("未知的策略 %q，可选值: %s", e.Strategy, strings.Join(names, ", ")))
+		}
+		if m := s.CanHandle(e); !m.OK() {
+			return nil, errcode.Wrap(errcode.NoStrategy, fmt.Errorf("策略 %s 无法处理该请求: %s", s.GetName(), m.Reason))
+		}
+		return s, nil
+	}
+
+	strategies, matches := sm.candidates(e)
+	if len(strategies) == 0 || !matches[0].OK() {
+		return nil, errcode.Wrap(errcode.NoStrategy, fmt.Errorf("no strategy found to handle the event"))
+	}
+	return strategies[0], nil
+}
+
+// explain 输出每个策略的匹配结果和最终选中的策略，不执行策略
+func (sm *StrategyManager) explain(e *Event) error {
+	selected, err := sm.selectStrategy(e)
+	strategies, matches := sm.candidates(e)
+	candidates := make([]output.Candidate, len(strategies))
+	for i, s := range strategies {
+		candidates[i] = output.Candidate{
+			Strategy: s.GetName(),
+			Score:    matches[i].Score,
+			Reason:   matches[i].Reason,
+			Selected: s == selected,
+		}
+	}
+	e.Out().Explain(candidates)
+	return err
+}
@@ -0,0 +1,145 @@
+package strategy
+
+import (
+	"fmt"
+	"github.com/MenciusCheng/go-cli/util/goref"
+	"github.com/MenciusCheng/go-cli/util/lang"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"os"
+	"time"
+)
+
+// Handler 处理事件
+type Handler func(e *Event) error
+
+// Middleware 中间件，包装下一层处理函数，在策略执行前后处理通用逻辑
+type Middleware func(next Handler) Handler
+
+// EditHook 策略写入文件后调用，path 为写入的文件路径
+type EditHook func(e *Event, path string) error
+
+// Use 注册中间件，先注册的中间件在外层，最内层为策略的选择和执行
+func (sm *StrategyManager) Use(middlewares ...Middleware) {
+	sm.middlewares = append(sm.middlewares, middlewares...)
+}
+
+// Edited 策略写入文件后调用，依次执行已注册的 EditHook
+func (e *Event) Edited(path string) error {
+	for _, hook := range e.EditHooks {
+		if err := hook(e, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Timing 记录事件处理耗时，在 debug 级别输出
+func Timing(next Handler) Handler {
+	return func(e *Event) error {
+		start := time.Now()
+		err := next(e)
+		logger.Debugf("处理耗时: %s\n", time.Since(start).Round(time.Millisecond))
+		return err
+	}
+}
+
+// ResolveAPIKeys 补全实际使用的提供方未通过命令行指定的 api key，见 Event.ResolveApiKey
+func ResolveAPIKeys(next Handler) Handler {
+	return func(e *Event) error {
+		if err := e.ResolveApiKey(); err != nil {
+			return fmt.Errorf("preprocess failed: %w", err)
+		}
+		return next(e)
+	}
+}
+
+// LoadFileContext 补充文件内容、选中文本、文件语言和文件修改时间
+func LoadFileContext(next Handler) Handler {
+	return func(e *Event) error {
+		if err := loadFileContext(e); err != nil {
+			return fmt.Errorf("preprocess failed: %w", err)
+		}
+		return next(e)
+	}
+}
+
+// ResolveRefs 选中 Go 代码时，解析选中代码引用的类型、函数和常量，将定义补充到 RefStruct
+// 解析失败不影响执行，只输出调试日志
+func ResolveRefs(next Handler) Handler {
+	return func(e *Event) error {
+		if e.Explain || e.RefStruct != "" || e.Language != lang.Go || e.SelectedText == "" || e.FileText == "" {
+			return next(e)
+		}
+		start, end, err := e.SelectionRange()
+		if err == nil {
+			e.RefStruct, err = goref.Resolve(e.FilePath, e.FileText, start, end, e.RefStructSize())
+		}
+		if err != nil {
+			logger.Debugf("解析引用的定义失败: %v\n", err)
+		}
+		return next(e)
+	}
+}
+
+func loadFileContext(event *Event) error {
+	// 记录文件修改时间，用于写入前检测并发修改
+	if event.FilePath != "" {
+		if info, err := os.Stat(event.FilePath); err == nil {
+			event.FileModTime = info.ModTime()
+		}
+	}
+
+	if err := detectLanguage(event); err != nil {
+		return err
+	}
+
+	// 检查是否需要预处理
+	hasSelection := event.SelectionStartLine > 0 && event.SelectionEndLine > 0
+	if event.FilePath == "" || !hasSelection && event.CaretLine <= 0 {
+		return nil
+	}
+
+	// 如果 FileText 为空，读取完整文件内容
+	if event.FileText == "" {
+		fileContent, err := os.ReadFile(event.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", event.FilePath, err)
+		}
+		event.FileText = string(fileContent)
+	}
+
+	// 如果 SelectedText 为空，提取选中的内容：提供列号时精确到字符，否则按整行提取
+	if event.SelectedText == "" && hasSelection {
+		start, end, err := event.SelectionRange()
+		if err != nil {
+			return err
+		}
+		event.SelectedText = event.FileText[start:end]
+	}
+
+	return nil
+}
+
+// detectLanguage 未指定文件语言时，根据 modeline、扩展名和 shebang 识别
+func detectLanguage(event *Event) error {
+	if event.Language != "" {
+		event.Language = lang.Normalize(event.Language)
+		return nil
+	}
+	if event.FilePath == "" {
+		return nil
+	}
+	content := event.FileText
+	if content == "" {
+		data, err := os.ReadFile(event.FilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read file %s: %w", event.FilePath, err)
+		}
+		content = string(data)
+	}
+	event.Language = lang.Detect(event.FilePath, content)
+	if event.Language != "" {
+		logger.Debugf("文件语言: %s\n", event.Language)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package rule_strategy
+
+import (
+	"context"
+	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/extract"
+	"github.com/MenciusCheng/go-cli/util/fsutil"
+	"github.com/MenciusCheng/go-cli/util/journal"
+	"github.com/MenciusCheng/go-cli/util/lang"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/util/output"
+	"github.com/MenciusCheng/go-cli/util/renderer"
+	"github.com/MenciusCheng/go-cli/util/strategy"
+	"github.com/MenciusCheng/go-cli/util/strategy/code_strategy"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func NewRuleStrategy(spec *rule.Spec) strategy.Strategy {
+	return &RuleStrategy{spec: spec}
+}
+
+// RuleStrategy 规则目录中声明的策略
+type RuleStrategy struct {
+	spec *rule.Spec
+}
+
+func (s *RuleStrategy) CanHandle(e *strategy.Event) strategy.Match {
+	m := s.spec.Match
+	if m.Selection != nil {
+		if *m.Selection && e.SelectedText == "" {
+			return strategy.Reject("未选中代码")
+		}
+		if !*m.Selection && e.SelectedText != "" {
+			return strategy.Reject("选中了代码")
+		}
+	}
+	if len(m.Files) > 0 {
+		if e.FilePath == "" {
+			return strategy.Reject("未指定文件路径")
+		}
+		matched := false
+		for _, pattern := range m.Files {
+			if ok, _ := filepath.Match(pattern, filepath.Base(e.FilePath)); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return strategy.Reject(fmt.Sprintf("文件 %s 不匹配 %s", filepath.Base(e.FilePath), strings.Join(m.Files, ", ")))
+		}
+	}
+	if m.Language != "" && !lang.Match(m.Language, e.Language) {
+		return strategy.Reject(fmt.Sprintf("文件不是 %s 代码", m.Language))
+	}
+	if !s.spec.PromptMatch(e.Prompt) {
+		return strategy.Reject(fmt.Sprintf("提示词不匹配 %s", m.Prompt))
+	}
+	switch s.spec.Output {
+	case rule.OutputReplace, rule.OutputInsert:
+		if e.FilePath == "" {
+			return strategy.Reject(fmt.Sprintf("输出方式 %s 需要指定文件路径", s.spec.Output))
+		}
+	}
+	return strategy.Accept(s.spec.Priority, fmt.Sprintf("满足规则 %s 的匹配条件", s.spec.Path))
+}
+
+func (s *RuleStrategy) Handle(e *strategy.Event) error {
+	out := e.Out()
+	out.Infof("策略名称: %s\n", s.GetName())
+	out.Infof("规则文件: %s\n", s.spec.Path)
+
+	// 规则中指定的模型优先，未设置的系统提示词、温度等使用内置模板 front matter 中的默认值
+	// 切换提供方时原有的 baseURL 和模型属于其他提供方，不再使用
+	if s.spec.Provider != "" && s.spec.Provider != e.ResolveProvider() {
+		e.Provider = s.spec.Provider
+		e.BaseURL = ""
+		e.Model = ""
+	}
+	if s.spec.Model != "" {
+		e.Model = s.spec.Model
+	}
+	base := rule.CodeRule
+	if s.spec.Output == rule.OutputPrint {
+		base = rule.ChatRule
+	}
+	tmpl, err := e.LoadTemplate(base)
+	if err != nil {
+		return err
+	}
+	tmpl.TemplateMeta = tmpl.TemplateMeta.Merge(s.spec.TemplateMeta)
+	repairTmpl, err := e.LoadTemplate(rule.RepairRule)
+	if err != nil {
+		return err
+	}
+	client, err := e.NewChatModel()
+	if err != nil {
+		return err
+	}
+
+	if e.FilePath != "" && e.FileText == "" {
+		data, err := os.ReadFile(e.FilePath)
+		if err != nil {
+			return fmt.Errorf("读取文件失败: %w", err)
+		}
+		e.FileText = string(data)
+	}
+
+	eMap := e.ToMapByJSON()
+	render := renderer.New()
+	prompt, err := e.RenderPrompt(render, s.spec.Template, eMap, client, tmpl)
+	if err != nil {
+		return err
+	}
+	out.Prompt(prompt)
+
+	messages := tmpl.Messages(prompt)
+	out.Infof("\n=== 大模型回答 ===\n")
+	resp, err := s.stream(e, client, tmpl.Request(messages))
+	if err != nil {
+		return err
+	}
+
+	switch s.spec.Output {
+	case rule.OutputPrint:
+		return nil
+	case rule.OutputNewFile:
+		return s.writeNewFile(e, render, eMap, resp.Content, client.ModelName())
+	}
+
+	newContent, err := s.apply(e, resp.Content)
+	if err != nil {
+		return err
+	}
+	newContent, err = code_strategy.ValidateWithRepair(e, newContent, func(problems string) (string, error) {
+		repairPrompt, err := render.RenderString(repairTmpl.Body, map[string]interface{}{"problems": problems})
+		if err != nil {
+			return "", errcode.Wrap(errcode.Template, fmt.Errorf("渲染模板失败: %w", err))
+		}
+		messages = append(messages,
+			openai.Message{Role: openai.RoleAssistant, Content: resp.Content},
+			openai.Message{Role: openai.RoleUser, Content: repairPrompt},
+		)
+		resp, err = s.stream(e, client, tmpl.Request(messages))
+		if err != nil {
+			return "", err
+		}
+		return s.apply(e, resp.Content)
+	})
+	if err != nil {
+		return err
+	}
+	return code_strategy.CommitEdit(e, newContent, client.ModelName())
+}
+
+func (s *RuleStrategy) GetName() string {
+	return s.spec.Name
+}
+
+func (s *RuleStrategy) Description() string {
+	if s.spec.Description != "" {
+		return s.spec.Description
+	}
+	return fmt.Sprintf("规则 %s（输出方式 %s）", s.spec.Path, s.spec.Output)
+}
+
+// stream 流式请求大模型，回答按事件的输出模式输出
+func (s *RuleStrategy) stream(e *strategy.Event, client openai.ChatModel, req openai.ChatRequest) (*openai.ChatResponse, error) {
+	out := e.Out()
+	resp, err := client.Stream(context.Background(), req, out.Token)
+	if err != nil {
+		return nil, errcode.Wrap(errcode.Provider, err)
+	}
+	out.Answer(output.Answer{
+		Content:  resp.Content,
+		Provider: e.ResolveProvider(),
+		Model:    client.ModelName(),
+		Usage:    resp.Usage,
+	})
+	return resp, nil
+}
+
+// apply 从回答中提取代码，按输出方式替换或插入到文件内容中，返回修改后的完整内容
+func (s *RuleStrategy) apply(e *strategy.Event, answer string) (string, error) {
+	code, err := extract.Code(answer, e.Language)
+	if err != nil {
+		return "", errcode.Wrap(errcode.Extract, fmt.Errorf("提取代码失败，未写入文件: %w", err))
+	}
+	start, end, err := e.SelectionRange()
+	if err != nil {
+		return "", err
+	}
+	if s.spec.Output == rule.OutputReplace {
+		// 只有光标没有选中区域时范围为空，替换整个文件
+		if start == end {
+			start, end = 0, len(e.FileText)
+		}
+		return code_strategy.ReplaceCode(e.FileText, start, end, code), nil
+	}
+
+	// 插入到光标处，未提供光标时插入到选中区域之后
+	offset, ok, err := e.CaretOffset()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		offset = end
+		if e.SelectedText == "" {
+			offset = len(e.FileText)
+		}
+	}
+	return e.FileText[:offset] + code + e.FileText[offset:], nil
+}
+
+// writeNewFile 将回答中的代码写入 newFile 指定的新文件，相对路径基于当前文件所在目录，并记录到编辑历史中以便撤销
+func (s *RuleStrategy) writeNewFile(e *strategy.Event, render *renderer.Renderer, eMap map[string]interface{}, answer, model string) error {
+	path, err := render.RenderString(s.spec.NewFile, eMap)
+	if err != nil {
+		return errcode.Wrap(errcode.Template, fmt.Errorf("渲染 newFile 失败: %w", err))
+	}
+	path = strings.TrimSpace(path)
+	if !filepath.IsAbs(path) && e.FilePath != "" {
+		path = filepath.Join(filepath.Dir(e.FilePath), path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return errcode.Wrap(errcode.Conflict, fmt.Errorf("文件 %s 已存在，未写入", path))
+	}
+
+	code, err := extract.Code(answer, lang.FromPath(path))
+	if err != nil {
+		return errcode.Wrap(errcode.Extract, fmt.Errorf("提取代码失败，未写入文件: %w", err))
+	}
+	code += "\n"
+
+	out := e.Out()
+	if e.DryRun {
+		out.Infof("\n将创建文件 %s（dry-run，不写入文件）\n", path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, []byte(code), 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := e.Edited(path); err != nil {
+		return err
+	}
+	// edit hook 可能再次修改文件，编辑历史记录最终的内容
+	if data, err := os.ReadFile(path); err == nil {
+		code = string(data)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	err = journal.New().Record(journal.Entry{
+		Path:    abs,
+		New:     []byte(code),
+		Prompt:  e.Prompt,
+		Model:   model,
+		Created: true,
+	})
+	if err != nil {
+		// 记录失败不影响本次修改
+		logger.Warnf("记录编辑历史失败: %v\n", err)
+	} else if len(code) > journal.MaxEntrySize {
+		logger.Warnf("文件过大，编辑历史未保存内容，无法通过 go-cli undo 撤销本次修改\n")
+	}
+	out.Edit(output.Edit{
+		FilePath:     path,
+		StartLine:    1,
+		EndLine:      0,
+		NewStartLine: 1,
+		NewEndLine:   strings.Count(code, "\n"),
+		Text:         strings.TrimSuffix(code, "\n"),
+		Applied:      true,
+	})
+	out.Infof("已创建文件: %s（可使用 go-cli undo 撤销）\n", path)
+	return nil
+}
@@ -0,0 +1,111 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultTabSize 默认制表符宽度，与 JetBrains IDE 的默认设置一致
+const DefaultTabSize = 4
+
+// HasColumns 是否提供了选中区域的列号
+func (e *Event) HasColumns() bool {
+	return e.SelectionStartColumn > 0 && e.SelectionEndColumn > 0
+}
+
+// SelectionRange 计算选中区域在 FileText 中的字节偏移 [start, end)
+// 提供列号时精确到字符，否则按整行计算；未提供行号时返回整个文件
+func (e *Event) SelectionRange() (int, int, error) {
+	if e.SelectionStartLine <= 0 || e.SelectionEndLine <= 0 {
+		return 0, len(e.FileText), nil
+	}
+
+	lines := strings.Split(e.FileText, "\n")
+	// 验证行号范围
+	if e.SelectionStartLine > len(lines) || e.SelectionEndLine > len(lines) {
+		return 0, 0, fmt.Errorf("selection line numbers out of range: file has %d lines, but selection is from line %d to %d",
+			len(lines), e.SelectionStartLine, e.SelectionEndLine)
+	}
+	if e.SelectionStartLine > e.SelectionEndLine {
+		return 0, 0, fmt.Errorf("invalid selection: start line %d is greater than end line %d",
+			e.SelectionStartLine, e.SelectionEndLine)
+	}
+
+	// 每行起始位置的字节偏移
+	lineStart := func(line int) int {
+		offset := 0
+		for _, l := range lines[:line-1] {
+			offset += len(l) + 1
+		}
+		return offset
+	}
+
+	if !e.HasColumns() {
+		// 整行模式：从开始行行首到结束行行尾（不含换行符）
+		start := lineStart(e.SelectionStartLine)
+		if e.SelectionStartLine == e.SelectionEndLine && e.SelectedText == "" {
+			// 单行、没有列号且没有选中的文本时视为只有光标，未选中
+			return start, start, nil
+		}
+		end := lineStart(e.SelectionEndLine) + len(lines[e.SelectionEndLine-1])
+		return start, end, nil
+	}
+
+	tabSize := e.TabSize
+	if tabSize <= 0 {
+		tabSize = DefaultTabSize
+	}
+	start := lineStart(e.SelectionStartLine) + ColumnToOffset(lines[e.SelectionStartLine-1], e.SelectionStartColumn, tabSize)
+	end := lineStart(e.SelectionEndLine) + ColumnToOffset(lines[e.SelectionEndLine-1], e.SelectionEndColumn, tabSize)
+	if end < start {
+		return 0, 0, fmt.Errorf("invalid selection: end position %d:%d is before start position %d:%d",
+			e.SelectionEndLine, e.SelectionEndColumn, e.SelectionStartLine, e.SelectionStartColumn)
+	}
+	return start, end, nil
+}
+
+// ColumnToOffset 将 JetBrains 的逻辑列号转换为行内字节偏移
+// 列号从 1 开始，每个字符占一列，制表符展开到下一个制表位；超出行尾时返回行尾
+func ColumnToOffset(line string, column, tabSize int) int {
+	col := 1
+	for offset, r := range line {
+		if col >= column {
+			return offset
+		}
+		if r == '\t' {
+			col = ((col-1)/tabSize+1)*tabSize + 1
+		} else {
+			col++
+		}
+	}
+	return len(line)
+}
+
+// CaretOffset 计算光标在 FileText 中的字节偏移
+// 优先使用光标行列号，其次使用空选区（开始位置与结束位置相同）的位置
+func (e *Event) CaretOffset() (int, bool, error) {
+	line, column := e.CaretLine, e.CaretColumn
+	if line <= 0 && e.HasColumns() && e.SelectionStartLine == e.SelectionEndLine && e.SelectionStartColumn == e.SelectionEndColumn {
+		line, column = e.SelectionStartLine, e.SelectionStartColumn
+	}
+	if line <= 0 {
+		return 0, false, nil
+	}
+
+	lines := strings.Split(e.FileText, "\n")
+	if line > len(lines) {
+		return 0, false, fmt.Errorf("caret line %d out of range: file has %d lines", line, len(lines))
+	}
+	offset := 0
+	for _, l := range lines[:line-1] {
+		offset += len(l) + 1
+	}
+	if column <= 0 {
+		column = 1
+	}
+	tabSize := e.TabSize
+	if tabSize <= 0 {
+		tabSize = DefaultTabSize
+	}
+	return offset + ColumnToOffset(lines[line-1], column, tabSize), true, nil
+}
@@ -3,24 +3,59 @@ package strategy
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/MenciusCheng/go-cli/rule"
+	"github.com/MenciusCheng/go-cli/util/config"
+	"github.com/MenciusCheng/go-cli/util/credential"
+	"github.com/MenciusCheng/go-cli/util/errcode"
+	"github.com/MenciusCheng/go-cli/util/logger"
+	"github.com/MenciusCheng/go-cli/util/openai"
+	"github.com/MenciusCheng/go-cli/util/output"
+	"github.com/MenciusCheng/go-cli/util/terminal"
 	"os"
 	"strings"
+	"time"
 )
 
 // Event 事件
 type Event struct {
-	Prompt               string `json:"prompt"`               // 用户输入的提示信息
-	FileDir              string `json:"fileDir"`              // 文件所在目录
-	FilePath             string `json:"filePath"`             // 文件完整路径
-	SelectionStartLine   int    `json:"selectionStartLine"`   // 选中文本开始行号
-	SelectionEndLine     int    `json:"selectionEndLine"`     // 选中文本结束行号
-	SelectionStartColumn int    `json:"selectionStartColumn"` // 选中文本开始列号
-	SelectionEndColumn   int    `json:"selectionEndColumn"`   // 选中文本结束列号
-	SelectedText         string `json:"selectedText"`         // 选中的文本内容
-	FileText             string `json:"fileText"`             // 完整文件内容
-	DeepseekApiKey       string `json:"deepseekApiKey"`       // deepseek api key
-	QwenApiKey           string `json:"qwenApiKey"`           // qwen api key
-	RefStruct            string `json:"refStruct"`            // 参考结构体定义
+	Prompt               string            `json:"prompt"`                   // 用户输入的提示信息
+	FileDir              string            `json:"fileDir"`                  // 文件所在目录
+	FilePath             string            `json:"filePath"`                 // 文件完整路径
+	SelectionStartLine   int               `json:"selectionStartLine"`       // 选中文本开始行号
+	SelectionEndLine     int               `json:"selectionEndLine"`         // 选中文本结束行号
+	SelectionStartColumn int               `json:"selectionStartColumn"`     // 选中文本开始列号
+	SelectionEndColumn   int               `json:"selectionEndColumn"`       // 选中文本结束列号
+	CaretLine            int               `json:"caretLine"`                // 光标所在行号
+	CaretColumn          int               `json:"caretColumn"`              // 光标所在列号
+	SelectedText         string            `json:"selectedText"`             // 选中的文本内容
+	FileText             string            `json:"fileText"`                 // 完整文件内容
+	Language             string            `json:"language"`                 // 文件语言，如 go、sql、shell，为空时自动识别
+	DeepseekApiKey       credential.Secret `json:"deepseekApiKey"`           // deepseek api key，序列化时脱敏
+	QwenApiKey           credential.Secret `json:"qwenApiKey"`               // qwen api key，序列化时脱敏
+	Provider             string            `json:"provider"`                 // 大模型提供方
+	Model                string            `json:"model"`                    // 模型名称
+	BaseURL              string            `json:"baseURL"`                  // 自定义服务地址，如本地 Ollama
+	Temperature          *float32          `json:"temperature,omitempty"`    // 采样温度，为空时使用策略默认值
+	MaxContextSize       int               `json:"maxContextSize,omitempty"` // 最大上下文长度（字符数），为 0 时只按模型的上下文窗口裁剪文件内容
+	Preview              bool              `json:"preview"`                  // 写入文件前展示差异并确认
+	DryRun               bool              `json:"dryRun"`                   // 只展示差异，不写入文件
+	Validate             bool              `json:"validate"`                 // 写入前校验 Go 代码语法并格式化
+	Build                bool              `json:"build"`                    // 写入前执行 go build 校验
+	Vet                  bool              `json:"vet"`                      // 写入前执行 go vet 校验
+	RepairRounds         int               `json:"repairRounds"`             // 校验失败时让大模型修复的最大轮数
+	ResponseFormat       string            `json:"responseFormat"`           // 代码补全的返回格式：replace、search-replace、diff
+	Interactive          bool              `json:"interactive"`              // 进入多轮交互式对话
+	SessionID            string            `json:"sessionID"`                // 继续的会话编号，为空时创建新会话
+	Format               string            `json:"format"`                   // 回答的输出格式：raw、markdown、json，为空时自动选择
+	OutputMode           string            `json:"outputMode"`               // 输出模式：text、json、ndjson
+	Strategy             string            `json:"strategy"`                 // 指定使用的策略，为空时自动选择，选中后为实际使用的策略
+	Explain              bool              `json:"explain"`                  // 只输出每个策略的匹配结果，不执行策略
+	Output               *output.Writer    `json:"-"`                        // 输出，为空时使用文本输出
+	TabSize              int               `json:"tabSize"`                  // 制表符宽度，用于换算列号
+	FileModTime          time.Time         `json:"-"`                        // 读取文件时的修改时间
+	RefStruct            string            `json:"refStruct"`                // 选中代码引用的类型、函数和常量的定义，选中 Go 代码时自动解析
+	Hooks                []config.Hook     `json:"-"`                        // 配置文件中声明的 hook
+	EditHooks            []EditHook        `json:"-"`                        // 策略写入文件后调用，由中间件注册
 }
 
 func (e *Event) ToMapByJSON() map[string]interface{} {
@@ -30,26 +65,212 @@ func (e *Event) ToMapByJSON() map[string]interface{} {
 	return result
 }
 
+// DefaultProvider 默认的大模型提供方
+const DefaultProvider = "deepseek"
+
+// ApiKey 获取指定提供方对应的 api key
+func (e *Event) ApiKey(provider string) string {
+	switch strings.ToLower(provider) {
+	case "deepseek":
+		return string(e.DeepseekApiKey)
+	case "qwen":
+		return string(e.QwenApiKey)
+	}
+	return ""
+}
+
+// setApiKey 设置指定提供方对应的 api key
+func (e *Event) setApiKey(provider, key string) {
+	switch strings.ToLower(provider) {
+	case "deepseek":
+		e.DeepseekApiKey = credential.Secret(key)
+	case "qwen":
+		e.QwenApiKey = credential.Secret(key)
+	}
+}
+
+// ResolveApiKey 补全实际使用的提供方未通过命令行指定的 api key，优先级：命令行参数 > 环境变量 > 凭证存储
+//   - 不需要 api key 的提供方（如本地模型）不读取环境变量和凭证存储
+//   - 未指定提供方且命令行没有指定 api key 时，按 credential.Providers 的顺序查找，使用第一个已配置 api key 的提供方，凭证存储出错时只输出警告
+func (e *Event) ResolveApiKey() error {
+	if e.Provider == "" {
+		for _, provider := range credential.Providers {
+			if e.ApiKey(provider) != "" {
+				return nil
+			}
+		}
+		for _, provider := range credential.Providers {
+			key, err := lookupApiKey(provider)
+			if err != nil {
+				logger.Warnf("读取 %s api key 失败: %v\n", provider, err)
+				continue
+			}
+			if key != "" {
+				e.setApiKey(provider, key)
+				return nil
+			}
+		}
+		return nil
+	}
+	p, ok := openai.GetProvider(e.Provider)
+	if !ok || !p.Capabilities().RequiresApiKey || e.ApiKey(e.Provider) != "" {
+		return nil
+	}
+	key, err := lookupApiKey(e.Provider)
+	if err != nil {
+		return fmt.Errorf("读取 %s api key 失败: %w", e.Provider, err)
+	}
+	e.setApiKey(e.Provider, key)
+	return nil
+}
+
+// lookupApiKey 依次从环境变量和凭证存储中查找 api key
+func lookupApiKey(provider string) (string, error) {
+	if _, v := credential.LookupEnv(provider); v != "" {
+		return v, nil
+	}
+	key, _, err := credential.Lookup(provider)
+	return key, err
+}
+
+// ResolveProvider 获取实际使用的提供方
+// 未指定提供方时，优先使用已配置 api key 的提供方：deepseek > qwen
+func (e *Event) ResolveProvider() string {
+	if e.Provider != "" {
+		return e.Provider
+	}
+	if e.DeepseekApiKey == "" && e.QwenApiKey != "" {
+		return "qwen"
+	}
+	return DefaultProvider
+}
+
+// LoadTemplate 加载规则模板，优先使用文件语言的模板，命令行参数和配置文件未指定模型时使用模板 front matter 中的模型
+// 需要在 NewChatModel 之前调用
+func (e *Event) LoadTemplate(name string) (*rule.Template, error) {
+	t, err := rule.LoadTemplate(name, e.Language)
+	if err != nil {
+		return nil, errcode.Wrap(errcode.Template, err)
+	}
+	logger.Debugf("使用规则模板: %s（%s）\n", t.Name, t.Source())
+	if e.Model == "" {
+		e.Model = t.Model
+	}
+	return t, nil
+}
+
+// NewChatModel 根据事件中的提供方和模型参数创建大模型客户端，策略修改了提供方时补全对应的 api key
+func (e *Event) NewChatModel() (openai.ChatModel, error) {
+	if err := e.ResolveApiKey(); err != nil {
+		return nil, errcode.Wrap(errcode.Config, err)
+	}
+	provider := e.ResolveProvider()
+	m, err := openai.NewChatModel(provider, openai.Options{
+		ApiKey:      e.ApiKey(provider),
+		Model:       e.Model,
+		BaseURL:     e.BaseURL,
+		Temperature: e.Temperature,
+	})
+	if err != nil {
+		return nil, errcode.Wrap(errcode.Config, err)
+	}
+	logger.Debugf("使用模型: %s/%s %s\n", provider, m.ModelName(), e.BaseURL)
+	return m, nil
+}
+
+// 回答的输出格式
+const (
+	FormatRaw      = "raw"      // 原样输出
+	FormatMarkdown = "markdown" // 在终端中渲染 markdown
+	FormatJSON     = "json"     // 回答结束后输出 json
+)
+
+// Formats 支持的回答输出格式
+var Formats = []string{FormatRaw, FormatMarkdown, FormatJSON}
+
+// OutputFormat 获取实际使用的输出格式
+// 未指定时，标准输出为终端则渲染 markdown，输出到管道或 IDE 控制台时原样输出
+func (e *Event) OutputFormat() (string, error) {
+	switch e.Format {
+	case "":
+		if terminal.ColorEnabled(os.Stdout) {
+			return FormatMarkdown, nil
+		}
+		return FormatRaw, nil
+	case FormatRaw, FormatMarkdown, FormatJSON:
+		return e.Format, nil
+	}
+	return "", errcode.Wrap(errcode.InvalidArgument,
+		fmt.Errorf("不支持的输出格式 %q，可选值: %s", e.Format, strings.Join(Formats, ", ")))
+}
+
+// InitOutput 根据输出模式和回答格式创建输出，--format json 等同于 --output json
+func (e *Event) InitOutput() error {
+	format, err := e.OutputFormat()
+	if err != nil {
+		return err
+	}
+	mode := e.OutputMode
+	if mode == "" && format == FormatJSON {
+		mode = output.JSON
+	}
+	out, err := output.New(mode, os.Stdout, format == FormatMarkdown)
+	if err != nil {
+		return err
+	}
+	if e.Preview && out.Structured() {
+		return errcode.Wrap(errcode.InvalidArgument, fmt.Errorf("--preview 需要交互确认，不能与 --output %s 同时使用", mode))
+	}
+	e.Output = out
+	return nil
+}
+
+// Out 获取事件的输出，未初始化时使用文本输出
+func (e *Event) Out() *output.Writer {
+	if e.Output == nil {
+		e.Output, _ = output.New(output.Text, os.Stdout, false)
+	}
+	return e.Output
+}
+
+// DefaultMaxContextSize 未设置 MaxContextSize 时用于计算 RefStruct 的最大长度（字符数）
+// 文件内容、光标前后内容和对话附件按模型的上下文窗口计算 token 预算，见 ContextTokens
+const DefaultMaxContextSize = 100000
+
+// RefStructSize RefStruct 的最大长度（字符数），为最大上下文长度的四分之一
+func (e *Event) RefStructSize() int {
+	limit := e.MaxContextSize
+	if limit <= 0 {
+		limit = DefaultMaxContextSize
+	}
+	return limit / 4
+}
+
 // Strategy 策略接口
 type Strategy interface {
-	// CanHandle 判断是否能处理该事件
-	CanHandle(e *Event) bool
+	// CanHandle 判断是否能处理该事件，返回匹配得分和原因
+	CanHandle(e *Event) Match
 	// Handle 处理事件
 	Handle(e *Event) error
 	// GetName 获取策略名称
 	GetName() string
+	// Description 策略说明，用于 go-cli strategies 列出策略
+	Description() string
 }
 
 // StrategyManager 策略管理器
 type StrategyManager struct {
-	strategies []Strategy
+	strategies  []Strategy
+	middlewares []Middleware
 }
 
-// NewStrategyManager 创建策略管理器
+// NewStrategyManager 创建策略管理器，默认注册补全 api key、读取文件上下文、运行 hook 和计时的中间件
 func NewStrategyManager() *StrategyManager {
-	return &StrategyManager{
+	sm := &StrategyManager{
 		strategies: make([]Strategy, 0),
 	}
+	sm.Use(Timing, ResolveAPIKeys, LoadFileContext, ResolveRefs, RunHooks)
+	return sm
 }
 
 // RegisterStrategy 注册策略
@@ -64,80 +285,38 @@ func (sm *StrategyManager) RegisterStrategies(strategies ...Strategy) {
 	}
 }
 
-// HandleEvent 处理事件
+// HandleEvent 处理事件，处理结果和错误按事件的输出模式输出
 func (sm *StrategyManager) HandleEvent(event *Event) error {
-	// 预处理事件
-	if err := sm.preprocess(event); err != nil {
-		return fmt.Errorf("preprocess failed: %w", err)
-	}
-
-	// 遍历所有策略，找到第一个能处理该事件的策略并执行
-	for _, strategy := range sm.strategies {
-		if strategy.CanHandle(event) {
-			return strategy.Handle(event)
+	if event.Output == nil {
+		if err := event.InitOutput(); err != nil {
+			return err
 		}
 	}
-
-	// 如果没有找到合适的策略，返回错误
-	return fmt.Errorf("no strategy found to handle the event")
+	return event.Output.Close(sm.handle(event))
 }
 
-// preprocess 预处理事件，补充文件内容和选中文本
-func (sm *StrategyManager) preprocess(event *Event) error {
-
-	if event.DeepseekApiKey == "" {
-		// 检查API环境变量
-		apiKey := os.Getenv("DEEPSEEK_API_KEY")
-		if apiKey != "" {
-			event.DeepseekApiKey = apiKey
-		}
-	}
-
-	// 检查是否需要预处理
-	if event.FilePath == "" || event.SelectionStartLine <= 0 || event.SelectionEndLine <= 0 {
-		return nil
+// handle 依次经过中间件后选择策略并执行
+func (sm *StrategyManager) handle(event *Event) error {
+	h := sm.dispatch
+	for i := len(sm.middlewares) - 1; i >= 0; i-- {
+		h = sm.middlewares[i](h)
 	}
+	return h(event)
+}
 
-	// 如果 FileText 或 SelectedText 不为空，则不需要预处理
-	if event.FileText != "" || event.SelectedText != "" {
-		return nil
+// dispatch 选择策略并执行，是中间件链的最内层
+func (sm *StrategyManager) dispatch(event *Event) error {
+	if event.Explain {
+		return sm.explain(event)
 	}
 
-	// 读取文件内容
-	fileContent, err := os.ReadFile(event.FilePath)
+	// 选择得分最高的策略或用户指定的策略并执行
+	strategy, err := sm.selectStrategy(event)
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", event.FilePath, err)
-	}
-
-	// 将文件内容转换为字符串
-	fileText := string(fileContent)
-
-	// 按行分割，保持原始的换行符和空白行
-	lines := strings.Split(fileText, "\n")
-
-	// 如果 FileText 为空，设置完整文件内容
-	if event.FileText == "" {
-		event.FileText = fileText
-	}
-
-	// 如果 SelectedText 为空，提取选中的行内容
-	if event.SelectedText == "" &&
-		(event.SelectionStartLine < event.SelectionEndLine || event.SelectionStartLine == event.SelectionEndLine && event.SelectionStartColumn < event.SelectionEndColumn) {
-		// 验证行号范围
-		if event.SelectionStartLine > len(lines) || event.SelectionEndLine > len(lines) {
-			return fmt.Errorf("selection line numbers out of range: file has %d lines, but selection is from line %d to %d",
-				len(lines), event.SelectionStartLine, event.SelectionEndLine)
-		}
-
-		if event.SelectionStartLine > event.SelectionEndLine {
-			return fmt.Errorf("invalid selection: start line %d is greater than end line %d",
-				event.SelectionStartLine, event.SelectionEndLine)
-		}
-
-		// 提取选中的行（注意：SelectionStartLine 从1开始，所以需要减1）
-		selectedLines := lines[event.SelectionStartLine-1 : event.SelectionEndLine]
-		event.SelectedText = strings.Join(selectedLines, "\n")
+		return err
 	}
-
-	return nil
+	logger.Debugf("命中策略: %s\n", strategy.GetName())
+	event.Strategy = strategy.GetName()
+	event.Output.Strategy(strategy.GetName())
+	return strategy.Handle(event)
 }
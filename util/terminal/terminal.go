@@ -0,0 +1,39 @@
+package terminal
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ANSI 颜色
+const (
+	Reset     = "\033[0m"
+	Bold      = "\033[1m"
+	Dim       = "\033[2m"
+	Italic    = "\033[3m"
+	Underline = "\033[4m"
+	Red       = "\033[31m"
+	Green     = "\033[32m"
+	Yellow    = "\033[33m"
+	Blue      = "\033[34m"
+	Magenta   = "\033[35m"
+	Cyan      = "\033[36m"
+)
+
+// IsTerminal 判断文件是否为终端
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// ColorEnabled 判断输出到 f 时是否启用颜色
+// 设置 NO_COLOR 时禁用，设置 FORCE_COLOR 时启用，否则仅在终端中启用
+func ColorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if os.Getenv("FORCE_COLOR") != "" {
+		return true
+	}
+	return IsTerminal(f)
+}